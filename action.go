@@ -1,5 +1,7 @@
 package main
 
+import "fmt"
+
 type Action int8
 
 const (
@@ -16,6 +18,26 @@ const (
 	Reset
 	Pause
 	MenuConfirm
+	// Closes the game. Handled by the app rather than passed to scenes.
+	Exit
+	// Restarts with the same pieces as the last game
+	RetrySeed
+	// Practice mode commands, ignored by every other objective
+	PracticeUndo
+	PracticeRedo
+	PracticeMark
+	PracticeLoad
+	PracticeReroll
+	PracticeGravity
+	// Puts a piece into the next slot of the practice queue, in the order of
+	// PIECE_LETTERS
+	QueueI
+	QueueJ
+	QueueL
+	QueueO
+	QueueS
+	QueueT
+	QueueZ
 )
 
 var ActionNames = []string{
@@ -32,6 +54,21 @@ var ActionNames = []string{
 	"Reset",
 	"Pause",
 	"MenuConfirm",
+	"Exit",
+	"RetrySeed",
+	"PracticeUndo",
+	"PracticeRedo",
+	"PracticeMark",
+	"PracticeLoad",
+	"PracticeReroll",
+	"PracticeGravity",
+	"QueueI",
+	"QueueJ",
+	"QueueL",
+	"QueueO",
+	"QueueS",
+	"QueueT",
+	"QueueZ",
 }
 
 type ReplayAction struct {
@@ -42,3 +79,28 @@ type ReplayAction struct {
 func (a Action) ToString() string {
 	return ActionNames[a]
 }
+
+func ParseAction(name string) (Action, bool) {
+	for i, n := range ActionNames {
+		if n == name {
+			return Action(i), true
+		}
+	}
+	return 0, false
+}
+
+func (a Action) MarshalText() ([]byte, error) {
+	if int(a) < 0 || int(a) >= len(ActionNames) {
+		return nil, fmt.Errorf("Invalid action %d", a)
+	}
+	return []byte(a.ToString()), nil
+}
+
+func (a *Action) UnmarshalText(text []byte) error {
+	act, ok := ParseAction(string(text))
+	if !ok {
+		return fmt.Errorf("Invalid action %q", text)
+	}
+	*a = act
+	return nil
+}
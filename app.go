@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -10,6 +11,9 @@ import (
 
 const TIME_SCALE float64 = 1
 
+// How long toast messages stay on screen
+const TOAST_DURATION = 4 * time.Second
+
 type App struct {
 	CurrentScene Scene
 	NextScene    Scene
@@ -27,6 +31,11 @@ type App struct {
 	Logger        *log.Logger
 
 	Audio AudioService
+
+	Settings *Settings
+
+	toast        string
+	toastExpires time.Time
 }
 
 func NewApp() *App {
@@ -41,6 +50,7 @@ func NewApp() *App {
 
 	Screen.SetStyle(defStyle)
 	Screen.EnableMouse()
+	Screen.EnableFocus()
 	Screen.EnablePaste()
 	Screen.Clear()
 
@@ -48,36 +58,8 @@ func NewApp() *App {
 		CurrentScene: &NullScene{},
 		DefaultStyle: tcell.StyleDefault.Background(tcell.ColorReset).
 			Foreground(tcell.ColorReset),
-		keyActionMap:  make(map[tcell.Key]Action),
-		runeActionMap: make(map[rune]Action),
 	}
 
-	app.keyActionMap[tcell.KeyLeft] = MoveLeft
-	app.keyActionMap[tcell.KeyRight] = MoveRight
-	app.keyActionMap[tcell.KeyUp] = MoveUp
-	app.keyActionMap[tcell.KeyDown] = MoveDown
-	app.keyActionMap[tcell.KeyEnter] = MenuConfirm
-
-	app.runeActionMap[' '] = HardDrop
-	app.runeActionMap['f'] = ToggleSuper
-	app.runeActionMap['F'] = ToggleSuper
-
-	app.runeActionMap['z'] = RotateCCW
-	app.runeActionMap['Z'] = RotateCCW
-	app.runeActionMap['x'] = RotateCW
-	app.runeActionMap['X'] = RotateCW
-	app.runeActionMap['c'] = SwapHoldPiece
-	app.runeActionMap['C'] = SwapHoldPiece
-
-	app.runeActionMap['q'] = Quit
-	app.runeActionMap['Q'] = Quit
-	app.runeActionMap['r'] = Reset
-	app.runeActionMap['R'] = Reset
-	app.runeActionMap['p'] = Pause
-	app.runeActionMap['P'] = Pause
-
-	app.OpenMenuScene()
-
 	// Initialize logger
 	app.LogFileHandle, err = os.Create("logfile")
 	if err != nil {
@@ -88,9 +70,49 @@ func NewApp() *App {
 
 	app.Audio = MustCreateAudioEngine()
 
+	app.Settings, err = LoadSettings()
+	if err != nil {
+		app.ReportError("Could not load settings", err)
+	}
+	app.ApplySettings()
+
+	app.OpenMenuScene()
+
 	return app
 }
 
+// ApplySettings puts the key bindings, volumes and display options from the
+// current settings into effect.
+func (a *App) ApplySettings() {
+	a.keyActionMap = make(map[tcell.Key]Action)
+	a.runeActionMap = make(map[rune]Action)
+	for act, keys := range a.Settings.KeyBindings {
+		for _, kb := range keys {
+			if kb.Key == tcell.KeyRune {
+				a.runeActionMap[kb.Rune] = act
+			} else {
+				a.keyActionMap[kb.Key] = act
+			}
+		}
+	}
+
+	a.Audio.SetVolume(
+		float64(a.Settings.Audio.SoundVolume)/100,
+		float64(a.Settings.Audio.MusicVolume)/100,
+	)
+
+	Display = a.Settings.Display
+}
+
+// SaveSettings writes the current settings to disk, letting the player know
+// if that fails.
+func (a *App) SaveSettings() {
+	err := a.Settings.Save()
+	if err != nil {
+		a.ReportError("Could not save settings", err)
+	}
+}
+
 func (a *App) Quit() {
 	maybePanic := recover()
 	Screen.Fini()
@@ -126,18 +148,24 @@ func (a *App) Loop() {
 			case *tcell.EventResize:
 				Screen.Sync()
 			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
+				if kc, ok := a.CurrentScene.(KeyCapturer); ok && kc.CapturingKeys() {
+					a.CurrentScene.HandleEvent(ev)
+					continue
+				}
+
+				var action Action
+				var ok bool
+				if ev.Key() == tcell.KeyRune {
+					action, ok = a.runeActionMap[ev.Rune()]
+				} else {
+					action, ok = a.keyActionMap[ev.Key()]
+				}
+
+				if ok && action == Exit {
 					return
 				} else if ev.Key() == tcell.KeyCtrlL {
 					Screen.Sync()
 				} else {
-					var action Action
-					var ok bool
-					if ev.Key() == tcell.KeyRune {
-						action, ok = a.runeActionMap[ev.Rune()]
-					} else {
-						action, ok = a.keyActionMap[ev.Key()]
-					}
 					if ok {
 						a.CurrentScene.HandleAction(action)
 					}
@@ -172,12 +200,7 @@ func (a *App) Draw(lag float64) {
 		return
 	}
 
-	rr := Area{
-		X:      (sw - MIN_WIDTH) / 2,
-		Y:      (sh - MIN_HEIGHT) / 2,
-		Width:  MIN_WIDTH,
-		Height: MIN_HEIGHT,
-	}
+	rr := ScreenArea(sw, sh)
 
 	BorderBox(Area{
 		X:      rr.X - 1,
@@ -187,9 +210,58 @@ func (a *App) Draw(lag float64) {
 	}, defStyle)
 
 	a.CurrentScene.Draw(sw, sh, rr, lag)
+	a.DrawToast(rr, sh)
 	Screen.Show()
 }
 
+// ShowToast displays a message on top of the current scene for a few
+// seconds. It stays up across scene changes.
+func (a *App) ShowToast(msg string) {
+	a.toast = msg
+	a.toastExpires = time.Now().Add(TOAST_DURATION)
+}
+
+// ReportError logs an error along with what was being done when it happened,
+// and lets the player know about it.
+func (a *App) ReportError(context string, err error) {
+	a.Logger.Printf("%v: %v\n", context, err)
+	a.ShowToast(fmt.Sprintf("%v: %v", context, err))
+}
+
+// Draws the toast over the bottom border, or over the last row of the scene
+// if the border is off screen.
+func (a *App) DrawToast(rr Area, sh int) {
+	if a.toast == "" {
+		return
+	}
+	if time.Now().After(a.toastExpires) {
+		a.toast = ""
+		return
+	}
+
+	text := []rune(" " + a.toast + " ")
+	if len(text) > rr.Width {
+		text = append(text[:rr.Width-4], []rune("... ")...)
+	}
+	SetCenteredString(
+		rr.X+rr.Width/2,
+		min(rr.Bottom(), sh-1),
+		string(text),
+		defStyle.Foreground(tcell.ColorRed).Reverse(true),
+	)
+}
+
+// ScreenArea returns the area scenes are drawn in, centered on a screen of
+// the given size.
+func ScreenArea(sw, sh int) Area {
+	return Area{
+		X:      (sw - MIN_WIDTH) / 2,
+		Y:      (sh - MIN_HEIGHT) / 2,
+		Width:  MIN_WIDTH,
+		Height: MIN_HEIGHT,
+	}
+}
+
 func (a *App) OpenMenuScene() {
 	menuScene := MenuScene{}
 	menuScene.Init(a)
@@ -197,6 +269,20 @@ func (a *App) OpenMenuScene() {
 	a.NextScene = &menuScene
 }
 
+func (a *App) OpenControlsScene() {
+	controlsScene := ControlsScene{}
+	controlsScene.Init(a)
+	a.NextScene = &controlsScene
+}
+
+// OpenOptionsScene opens the options, returning to the given scene once done.
+// With no scene to return to, the main menu is opened instead.
+func (a *App) OpenOptionsScene(back Scene) {
+	optionsScene := OptionsScene{}
+	optionsScene.Init(a, back)
+	a.NextScene = &optionsScene
+}
+
 func (a *App) OpenPreGameScene(
 	gts GlobalTetrisSettings,
 	oid ObjectiveID,
@@ -213,10 +299,18 @@ func (a *App) OpenPreGameScene(
 	a.NextScene = &preGameScene
 }
 
+func (a *App) OpenGhostRaceScene(ghost ReplayData) {
+	preGameScene := PreGameScene{}
+	preGameScene.InitGhostRace(a, ghost)
+
+	a.NextScene = &preGameScene
+}
+
 func (a *App) OpenGameScene(
 	gts GlobalTetrisSettings,
 	oid ObjectiveID,
 	obj ObjectiveSettings,
+	options ...GameSceneOption,
 ) {
 	gameScene := GameScene{}
 	gameScene.Init(
@@ -224,22 +318,63 @@ func (a *App) OpenGameScene(
 		gts,
 		oid,
 		obj,
+		options...,
 	)
 
 	a.NextScene = &gameScene
 }
 
+func (a *App) OpenGameSceneFromReplay(player *ReplayPlayer) {
+	gameScene := GameScene{}
+	gameScene.InitFromReplay(a, player)
+
+	a.NextScene = &gameScene
+}
+
+func (a *App) OpenResultsScene(game *GameScene, replayData *ReplayData) {
+	resultsScene := ResultsScene{}
+	resultsScene.Init(a, game, replayData)
+	a.NextScene = &resultsScene
+}
+
+func (a *App) OpenRecordsScene() {
+	recordsScene := RecordsScene{}
+	recordsScene.Init(a)
+	a.NextScene = &recordsScene
+}
+
+func (a *App) OpenPuzzleScene() {
+	puzzleScene := PuzzleScene{}
+	puzzleScene.Init(a)
+	a.NextScene = &puzzleScene
+}
+
+func (a *App) OpenDailyScene() {
+	dailyScene := DailyScene{}
+	dailyScene.Init(a)
+	a.NextScene = &dailyScene
+}
+
+func (a *App) OpenEditorScene() {
+	editorScene := EditorScene{}
+	editorScene.Init(a)
+	a.NextScene = &editorScene
+}
+
 func (a *App) OpenReplayBrowserScene() {
 	menuScene := ReplayBrowserScene{}
 	menuScene.Init(a)
 	a.CurrentScene = &menuScene
 }
 
-func (a *App) OpenReplayViewerScene(data ReplayData) {
+// OpenReplayViewerScene plays back a replay, returning to the given scene once
+// done. With no scene to return to, the main menu is opened instead.
+func (a *App) OpenReplayViewerScene(data ReplayData, back Scene) {
 	replayScene := ReplayViewerScene{}
 	replayScene.Init(
 		a,
 		data,
+		back,
 	)
 
 	a.NextScene = &replayScene
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Size of the recording, leaving room for the border drawn around the scene
+const CAST_WIDTH = MIN_WIDTH + 4
+const CAST_HEIGHT = MIN_HEIGHT + 4
+
+// How long the final frame stays on screen
+const CAST_END_HOLD_SECS = 2.0
+
+// Header of an asciicast v2 file
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env"`
+}
+
+// ExportAsciicast plays a replay headlessly, drawing it onto a simulated
+// screen every frameSkip frames, and writes the result as an asciicast v2
+// recording.
+func ExportAsciicast(rd *ReplayData, w io.Writer, frameSkip int) error {
+	if frameSkip < 1 {
+		return fmt.Errorf("frame skip must be at least 1")
+	}
+
+	sim := tcell.NewSimulationScreen("UTF-8")
+	err := sim.Init()
+	if err != nil {
+		return err
+	}
+	defer sim.Fini()
+	sim.SetSize(CAST_WIDTH, CAST_HEIGHT)
+
+	// The draw code renders to the global screen
+	oldScreen := Screen
+	Screen = sim
+	defer func() {
+		Screen = oldScreen
+	}()
+
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(castHeader{
+		Version:   2,
+		Width:     CAST_WIDTH,
+		Height:    CAST_HEIGHT,
+		Timestamp: time.Now().Unix(),
+		Env: map[string]string{
+			"TERM": "xterm-256color",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	writeFrame := func(rp *ReplayPlayer) error {
+		drawCastFrame(sim, rp)
+		return encoder.Encode([]any{
+			float64(rp.Frame()) / float64(FRAMES_PER_SECOND),
+			"o",
+			screenToANSI(sim),
+		})
+	}
+
+	rp := NewReplayPlayer(*rd)
+	rp.Start()
+	for !rp.Done() {
+		if rp.Frame()%int64(frameSkip) == 0 {
+			err = writeFrame(rp)
+			if err != nil {
+				return err
+			}
+		}
+		rp.Step()
+	}
+
+	err = writeFrame(rp)
+	if err != nil {
+		return err
+	}
+
+	// Empty event so players keep the final frame up for a while
+	return encoder.Encode([]any{
+		float64(rp.Frame())/float64(FRAMES_PER_SECOND) + CAST_END_HOLD_SECS,
+		"o",
+		"",
+	})
+}
+
+func drawCastFrame(sim tcell.SimulationScreen, rp *ReplayPlayer) {
+	sim.Clear()
+
+	rr := ScreenArea(CAST_WIDTH, CAST_HEIGHT)
+	BorderBox(Area{
+		X:      rr.X - 1,
+		Y:      rr.Y - 1,
+		Width:  rr.Width + 2,
+		Height: rr.Height + 2,
+	}, defStyle)
+
+	playingField := rr.Inset(BOARD_WIDTH, BOARD_HEIGHT+4)
+	rp.Field.Draw(CAST_WIDTH, CAST_HEIGHT, playingField, 0)
+	DrawStats(
+		rp.Objective.GetStats(),
+		playingField.X-2,
+		playingField.Bottom()-2,
+	)
+
+	sim.Show()
+}
+
+// screenToANSI redraws the whole simulated screen as text with ANSI escape
+// codes, only emitting a new style when it changes.
+func screenToANSI(sim tcell.SimulationScreen) string {
+	cells, width, height := sim.GetContents()
+
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+
+	lastStyle := tcell.StyleDefault
+	sb.WriteString("\x1b[0m")
+	for y := 0; y < height; y++ {
+		if y > 0 {
+			sb.WriteString("\r\n")
+		}
+		for x := 0; x < width; x++ {
+			cell := cells[y*width+x]
+			if cell.Style != lastStyle {
+				sb.WriteString(styleToSGR(cell.Style))
+				lastStyle = cell.Style
+			}
+
+			if len(cell.Runes) == 0 {
+				sb.WriteRune(' ')
+			} else {
+				sb.WriteString(string(cell.Runes))
+			}
+		}
+	}
+	sb.WriteString("\x1b[0m")
+
+	return sb.String()
+}
+
+func styleToSGR(style tcell.Style) string {
+	fg, bg, attrs := style.Decompose()
+
+	codes := []string{"0"}
+	if attrs&tcell.AttrBold != 0 {
+		codes = append(codes, "1")
+	}
+	if attrs&tcell.AttrDim != 0 {
+		codes = append(codes, "2")
+	}
+	if attrs&tcell.AttrUnderline != 0 {
+		codes = append(codes, "4")
+	}
+	if attrs&tcell.AttrReverse != 0 {
+		codes = append(codes, "7")
+	}
+
+	if r, g, b := fg.RGB(); r >= 0 {
+		codes = append(codes, fmt.Sprintf("38;2;%d;%d;%d", r, g, b))
+	}
+	if r, g, b := bg.RGB(); r >= 0 {
+		codes = append(codes, fmt.Sprintf("48;2;%d;%d;%d", r, g, b))
+	}
+
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
@@ -13,6 +13,8 @@ import (
 type AudioService interface {
 	PlaySound(name string)
 	StopSound(name string)
+	// Volumes range from 0 to 1
+	SetVolume(sound, music float64)
 }
 
 type NullAudioEngine struct {
@@ -21,6 +23,8 @@ type NullAudioEngine struct {
 type AudioEngine struct {
 	Context *audio.Context
 	Players map[string]*audio.Player
+	// Names of the players that loop music rather than play sound effects
+	Music map[string]bool
 }
 
 func (ne *NullAudioEngine) PlaySound(name string) {
@@ -29,6 +33,9 @@ func (ne *NullAudioEngine) PlaySound(name string) {
 func (ne *NullAudioEngine) StopSound(name string) {
 }
 
+func (ne *NullAudioEngine) SetVolume(sound, music float64) {
+}
+
 func MustCreateAudioEngine() *AudioEngine {
 	ae, err := CreateAudioEngine()
 	if err != nil {
@@ -42,6 +49,7 @@ func CreateAudioEngine() (*AudioEngine, error) {
 	ae := &AudioEngine{
 		Context: audio.NewContext(44100),
 		Players: make(map[string]*audio.Player),
+		Music:   make(map[string]bool),
 	}
 
 	sfxDir, err := os.Open("assets/sfx")
@@ -106,6 +114,7 @@ func CreateAudioEngine() (*AudioEngine, error) {
 		}
 
 		ae.Players[soundName] = player
+		ae.Music[soundName] = true
 	}
 	return ae, nil
 }
@@ -128,3 +137,13 @@ func (ae *AudioEngine) StopSound(name string) {
 
 	player.Pause()
 }
+
+func (ae *AudioEngine) SetVolume(sound, music float64) {
+	for name, player := range ae.Players {
+		if ae.Music[name] {
+			player.SetVolume(music)
+		} else {
+			player.SetVolume(sound)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const BOARD_DIR = "boards"
+
+const BOARD_FILE_EXTENSION = ".txt"
+
+// Grid value of a garbage cell. Pieces are stored as their index plus one.
+const GARBAGE_CELL = 8
+
+// Value of the hold piece when nothing is held
+const NO_HOLD_PIECE = 8
+
+// Letters for each piece, in the order of Pieces
+const PIECE_LETTERS = "IJLOSTZ"
+
+// Letter for garbage cells in board files
+const GARBAGE_LETTER = 'G'
+
+// Board is a position to start a game from: the visible part of the grid, the
+// held piece, and the pieces that come first in the queue.
+type Board struct {
+	Grid  Grid[int]
+	Hold  int
+	Queue []int
+}
+
+func NewBoard() *Board {
+	return &Board{
+		Grid: MakeGrid(BOARD_WIDTH, BOARD_HEIGHT, 0),
+		Hold: NO_HOLD_PIECE,
+	}
+}
+
+func (b *Board) Clone() *Board {
+	return &Board{
+		Grid:  b.Grid.Clone(),
+		Hold:  b.Hold,
+		Queue: slices.Clone(b.Queue),
+	}
+}
+
+// Validate checks that every cell, the hold piece and the queue hold values
+// the game knows how to play.
+func (b *Board) Validate() error {
+	for _, cell := range b.Grid.data {
+		if cell < 0 || cell > GARBAGE_CELL {
+			return fmt.Errorf("Invalid cell %d", cell)
+		}
+	}
+	if b.Hold != NO_HOLD_PIECE && (b.Hold < 0 || b.Hold >= len(Pieces)) {
+		return fmt.Errorf("Invalid hold piece %d", b.Hold)
+	}
+	for _, p := range b.Queue {
+		if p < 0 || p >= len(Pieces) {
+			return fmt.Errorf("Invalid queue piece %d", p)
+		}
+	}
+	return nil
+}
+
+// AddGarbageRow pushes the board up by a row and fills the bottom row with
+// garbage, leaving a hole in the given column. Cells pushed off the top are
+// lost.
+func (b *Board) AddGarbageRow(hole int) {
+	for y := 0; y < b.Grid.Height-1; y++ {
+		for x := 0; x < b.Grid.Width; x++ {
+			b.Grid.Set(x, y, b.Grid.MustGet(x, y+1))
+		}
+	}
+	for x := 0; x < b.Grid.Width; x++ {
+		cell := GARBAGE_CELL
+		if x == hole {
+			cell = 0
+		}
+		b.Grid.Set(x, b.Grid.Height-1, cell)
+	}
+}
+
+// PieceFromLetter returns the index of the piece with the given letter.
+func PieceFromLetter(r rune) (int, bool) {
+	idx := strings.IndexRune(PIECE_LETTERS, r)
+	return idx, idx >= 0
+}
+
+// FormatQueue writes pieces as letters, such as "TSZ".
+func FormatQueue(pieces []int) string {
+	var sb strings.Builder
+	for _, p := range pieces {
+		sb.WriteByte(PIECE_LETTERS[p])
+	}
+	return sb.String()
+}
+
+// ParseQueue reads pieces written as letters. Case and spaces don't matter.
+func ParseQueue(text string) ([]int, error) {
+	pieces := make([]int, 0, len(text))
+	for _, r := range strings.ToUpper(text) {
+		if r == ' ' {
+			continue
+		}
+		idx, ok := PieceFromLetter(r)
+		if !ok {
+			return nil, fmt.Errorf("Invalid piece %q", r)
+		}
+		pieces = append(pieces, idx)
+	}
+	return pieces, nil
+}
+
+func cellLetter(cell int) byte {
+	switch {
+	case cell == 0:
+		return '.'
+	case cell == GARBAGE_CELL:
+		return GARBAGE_LETTER
+	default:
+		return PIECE_LETTERS[cell-1]
+	}
+}
+
+// MarshalText writes the board as text: optional "hold:" and "queue:" lines
+// followed by the rows of the grid, top to bottom, with '.' for an empty
+// cell, a piece letter for a piece and 'G' for garbage. Empty rows at the top
+// are left out.
+func (b *Board) MarshalText() ([]byte, error) {
+	var sb strings.Builder
+	if b.Hold != NO_HOLD_PIECE {
+		fmt.Fprintf(&sb, "hold: %c\n", PIECE_LETTERS[b.Hold])
+	}
+	if len(b.Queue) > 0 {
+		fmt.Fprintf(&sb, "queue: %v\n", FormatQueue(b.Queue))
+	}
+
+	top := b.Grid.Height
+	for y := 0; y < b.Grid.Height; y++ {
+		for x := 0; x < b.Grid.Width; x++ {
+			if b.Grid.MustGet(x, y) != 0 {
+				top = min(top, y)
+			}
+		}
+	}
+	for y := top; y < b.Grid.Height; y++ {
+		for x := 0; x < b.Grid.Width; x++ {
+			sb.WriteByte(cellLetter(b.Grid.MustGet(x, y)))
+		}
+		sb.WriteByte('\n')
+	}
+
+	return []byte(sb.String()), nil
+}
+
+// UnmarshalText reads a board written by MarshalText. Rows line up with the
+// bottom of the board, so only the filled part has to be written. Lines
+// starting with '#' are comments.
+func (b *Board) UnmarshalText(text []byte) error {
+	*b = *NewBoard()
+
+	var rows []string
+	scanner := bufio.NewScanner(strings.NewReader(string(text)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, isField := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case isField && key == "hold":
+			if value == "" {
+				continue
+			}
+			hold, err := ParseQueue(value)
+			if err != nil || len(hold) != 1 {
+				return fmt.Errorf("Invalid hold piece %q", value)
+			}
+			b.Hold = hold[0]
+		case isField && key == "queue":
+			queue, err := ParseQueue(value)
+			if err != nil {
+				return err
+			}
+			b.Queue = queue
+		case isField:
+			return fmt.Errorf("Unknown field %q", key)
+		default:
+			if len(line) != BOARD_WIDTH {
+				return fmt.Errorf(
+					"Row %q should be %v cells wide", line, BOARD_WIDTH)
+			}
+			rows = append(rows, line)
+		}
+	}
+	if len(rows) > BOARD_HEIGHT {
+		return fmt.Errorf("Board has more than %v rows", BOARD_HEIGHT)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	letters := GridFromStrings(rows...)
+	offset := BOARD_HEIGHT - letters.Height
+	for y := 0; y < letters.Height; y++ {
+		for x := 0; x < letters.Width; x++ {
+			r := letters.MustGet(x, y)
+			cell := 0
+			if r == GARBAGE_LETTER {
+				cell = GARBAGE_CELL
+			} else if idx, ok := PieceFromLetter(r); ok {
+				cell = idx + 1
+			} else if r != '.' {
+				return fmt.Errorf("Invalid cell %q", r)
+			}
+			b.Grid.Set(x, y+offset, cell)
+		}
+	}
+	return nil
+}
+
+func boardPath(name string) string {
+	return filepath.Join(BOARD_DIR, name+BOARD_FILE_EXTENSION)
+}
+
+// ListBoards returns the names of the saved boards.
+func ListBoards() ([]string, error) {
+	entries, err := os.ReadDir(BOARD_DIR)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), BOARD_FILE_EXTENSION)
+		if ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func ReadBoardFile(name string) (*Board, error) {
+	data, err := os.ReadFile(boardPath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Board{}
+	err = b.UnmarshalText(data)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func SaveBoardFile(name string, b *Board) error {
+	err := ValidateReplayName(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := b.MarshalText()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(BOARD_DIR, 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(boardPath(name), data, 0644)
+}
+
+// LoadBoard sets up the field to start from a board. The board's queue comes
+// first, followed by the pieces from the randomizer.
+func (es *TetrisField) LoadBoard(b *Board) {
+	for y := 0; y < BOARD_HEIGHT; y++ {
+		for x := 0; x < BOARD_WIDTH; x++ {
+			es.grid.Set(x, y+BOARD_HEIGHT, b.Grid.MustGet(x, y))
+		}
+	}
+
+	es.holdPiece = b.Hold
+	if len(b.Queue) > 0 {
+		es.pieceGenerator = &ListPieceGenerator{
+			Pieces: slices.Clone(b.Queue),
+			Then:   es.pieceGenerator,
+		}
+		es.FillNextPieces()
+	}
+
+	es.maxStackHeight = 0
+	for y := 0; y < BOARD_HEIGHT; y++ {
+		for x := 0; x < BOARD_WIDTH; x++ {
+			if b.Grid.MustGet(x, y) != 0 {
+				es.maxStackHeight = max(es.maxStackHeight, BOARD_HEIGHT-y)
+			}
+		}
+	}
+}
@@ -27,6 +27,7 @@ func (cs *CheeseSettings) Init(es *TetrisField) Objective {
 			CreateElapsedTimeStat(es),
 			CreateLinesStat(es),
 			CreatePiecesStat(es),
+			CreateFinesseStat(es),
 		},
 	}
 
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type Command struct {
+	Name  string
+	Args  string
+	Usage string
+	Run   func(args []string) error
+}
+
+var Commands = []Command{
+	{
+		Name:  "export-json",
+		Args:  "<replay> [output]",
+		Usage: "Convert a replay to human-readable JSON",
+		Run: func(args []string) error {
+			return ConvertReplay(args, EncodeJSON)
+		},
+	},
+	{
+		Name:  "import-json",
+		Args:  "<replay.json> [output]",
+		Usage: "Convert a JSON replay back to the standard format",
+		Run: func(args []string) error {
+			return ConvertReplay(args, StdEncoder)
+		},
+	},
+	{
+		Name:  "export-cast",
+		Args:  "[-skip frames] <replay> [output.cast]",
+		Usage: "Render a replay to an asciinema recording",
+		Run:   ExportCast,
+	},
+	{
+		Name:  "export-fumen",
+		Args:  "[-board] <replay> [output]",
+		Usage: "Convert every placement of a replay, or a board file, to a Fumen",
+		Run:   ExportFumen,
+	},
+	{
+		Name:  "import-fumen",
+		Args:  "[-page n] <fumen> [output]",
+		Usage: "Convert a page of a Fumen to a board file",
+		Run:   ImportFumen,
+	},
+	{
+		Name:  "verify",
+		Args:  "<replay>...",
+		Usage: "Check that replays play back exactly as they were recorded",
+		Run:   VerifyReplays,
+	},
+	{
+		Name:  "delete",
+		Args:  "<name>...",
+		Usage: "Delete replays from the replay directory",
+		Run:   DeleteReplays,
+	},
+	{
+		Name:  "rename",
+		Args:  "<name> <new name>",
+		Usage: "Rename a replay in the replay directory",
+		Run:   RenameReplay,
+	},
+	{
+		Name:  "favorite",
+		Args:  "<name>...",
+		Usage: "Star or unstar replays so they are never pruned",
+		Run:   ToggleFavorites,
+	},
+	{
+		Name:  "prune",
+		Args:  "[-keep n] [-dry-run]",
+		Usage: "Delete old replays, keeping favorites, personal bests and the newest few of each mode",
+		Run:   PruneReplays,
+	},
+}
+
+// RunCommand runs the command named by the first argument and returns the
+// process exit code.
+func RunCommand(args []string) int {
+	for _, cmd := range Commands {
+		if cmd.Name != args[0] {
+			continue
+		}
+
+		err := cmd.Run(args[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", cmd.Name, err)
+			return 1
+		}
+		return 0
+	}
+
+	PrintUsage(os.Stderr)
+	return 2
+}
+
+func PrintUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %v [command]\n\n", os.Args[0])
+	fmt.Fprintf(w, "Starts the game when no command is given.\n\n")
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range Commands {
+		fmt.Fprintf(w, "  %v %v\n", cmd.Name, cmd.Args)
+		fmt.Fprintf(w, "        %v\n", cmd.Usage)
+	}
+}
+
+// Opens the named file for reading, or stdin if the name is "-".
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// Creates the named file for writing, or uses stdout if the name is "-" or
+// missing.
+func openOutput(args []string, idx int) (io.WriteCloser, error) {
+	if idx >= len(args) || args[idx] == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(args[idx])
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// ConvertReplay reads a replay in any format and writes it back out with the
+// given encoder.
+func ConvertReplay(args []string, encode ReplayEncoder) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("expected an input and an optional output file")
+	}
+
+	in, err := openInput(args[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	replayData, err := StdDecoder(in)
+	if err != nil {
+		return err
+	}
+
+	out, err := openOutput(args, 1)
+	if err != nil {
+		return err
+	}
+
+	err = encode(replayData, out)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// ExportCast renders a replay to an asciicast file, drawing one frame out of
+// every -skip frames.
+func ExportCast(args []string) error {
+	flags := flag.NewFlagSet("export-cast", flag.ContinueOnError)
+	frameSkip := flags.Int("skip", 2, "number of game frames per recorded frame")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = flags.Args()
+
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("expected a replay and an optional output file")
+	}
+
+	in, err := openInput(args[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	replayData, err := StdDecoder(in)
+	if err != nil {
+		return err
+	}
+
+	out, err := openOutput(args, 1)
+	if err != nil {
+		return err
+	}
+
+	err = ExportAsciicast(replayData, out, *frameSkip)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// ExportFumen writes a replay as a Fumen with a page for each placement, or a
+// board as a single page.
+func ExportFumen(args []string) error {
+	flags := flag.NewFlagSet("export-fumen", flag.ContinueOnError)
+	isBoard := flags.Bool("board", false, "read a board file instead of a replay")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = flags.Args()
+
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("expected an input and an optional output file")
+	}
+
+	in, err := openInput(args[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var pages []FumenPage
+	if *isBoard {
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return err
+		}
+		board := &Board{}
+		err = board.UnmarshalText(data)
+		if err != nil {
+			return err
+		}
+		pages = []FumenPage{board.FumenPage()}
+	} else {
+		replayData, err := StdDecoder(in)
+		if err != nil {
+			return err
+		}
+		pages = replayData.FumenPages()
+	}
+
+	out, err := openOutput(args, 1)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, EncodeFumen(pages))
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// ImportFumen writes one page of a Fumen as a board file.
+func ImportFumen(args []string) error {
+	flags := flag.NewFlagSet("import-fumen", flag.ContinueOnError)
+	page := flags.Int("page", 1, "page of the fumen to import")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = flags.Args()
+
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("expected a fumen and an optional output file")
+	}
+
+	pages, err := DecodeFumen(args[0])
+	if err != nil {
+		return err
+	}
+	board, err := BoardFromFumen(pages, *page-1)
+	if err != nil {
+		return err
+	}
+	data, err := board.MarshalText()
+	if err != nil {
+		return err
+	}
+
+	out, err := openOutput(args, 1)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(data)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// VerifyReplays simulates each replay headlessly and reports the ones that
+// don't reproduce the recorded game.
+func VerifyReplays(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected at least one replay")
+	}
+
+	failed := 0
+	for _, name := range args {
+		err := verifyReplay(name)
+		if err != nil {
+			fmt.Printf("%v: %v\n", name, err)
+			failed++
+		} else {
+			fmt.Printf("%v: ok\n", name)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v replays failed", failed, len(args))
+	}
+	return nil
+}
+
+func verifyReplay(name string) error {
+	in, err := openInput(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	replayData, err := StdDecoder(in)
+	if err != nil {
+		return err
+	}
+
+	if len(replayData.Checksums) == 0 {
+		return fmt.Errorf("replay has no checksums")
+	}
+
+	return replayData.Verify()
+}
+
+// DeleteReplays removes replays from the replay directory by name.
+func DeleteReplays(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected at least one replay")
+	}
+
+	// Check every name first, so a bad one doesn't leave the rest half done
+	for _, name := range args {
+		err := ValidateReplayName(name)
+		if err != nil {
+			return fmt.Errorf("%v: %w", name, err)
+		}
+	}
+
+	index, err := LoadReplayIndex()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range args {
+		err = index.DeleteReplay(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %v\n", name)
+	}
+	return nil
+}
+
+func RenameReplay(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected a replay and its new name")
+	}
+
+	index, err := LoadReplayIndex()
+	if err != nil {
+		return err
+	}
+
+	return index.RenameReplay(args[0], args[1])
+}
+
+func ToggleFavorites(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected at least one replay")
+	}
+
+	index, err := LoadReplayIndex()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range args {
+		err = ValidateReplayName(name)
+		if err != nil {
+			return fmt.Errorf("%v: %w", name, err)
+		}
+
+		_, err = os.Stat(replayPath(name))
+		if err != nil {
+			return err
+		}
+
+		err = index.ToggleFavorite(name)
+		if err != nil {
+			return err
+		}
+
+		if index.Favorites[name] {
+			fmt.Printf("Starred %v\n", name)
+		} else {
+			fmt.Printf("Unstarred %v\n", name)
+		}
+	}
+	return nil
+}
+
+// PruneReplays decodes every replay in the replay directory and deletes the
+// ones that aren't worth keeping.
+func PruneReplays(args []string) error {
+	flags := flag.NewFlagSet("prune", flag.ContinueOnError)
+	keep := flags.Int("keep", DEFAULT_PRUNE_KEEP, "number of recent replays to keep per mode")
+	dryRun := flags.Bool("dry-run", false, "list the replays that would be deleted")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+	if *keep < 0 {
+		return fmt.Errorf("keep must not be negative")
+	}
+
+	index, err := LoadReplayIndex()
+	if err != nil {
+		return err
+	}
+
+	entries, err := ListReplays()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		entry.Decode()
+	}
+
+	pruned := index.PlanPrune(entries, *keep)
+	for _, entry := range pruned {
+		if *dryRun {
+			fmt.Printf("Would delete %v\n", entry.Name)
+			continue
+		}
+
+		err = index.DeleteReplay(entry.Name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %v\n", entry.Name)
+	}
+
+	fmt.Printf("%v of %v replays pruned\n", len(pruned), len(entries))
+	return nil
+}
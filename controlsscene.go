@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Rows taken up by the title, help text and column headers
+const CONTROLS_HEADER_ROWS = 4
+
+// Width of the column holding action names
+const CONTROLS_ACTION_WIDTH = 16
+
+// Without a key for these, menus can't be used at all
+var ESSENTIAL_ACTIONS = []Action{MoveUp, MoveDown, MenuConfirm, Quit}
+
+type ControlsPrompt int8
+
+const (
+	NoControlsPrompt ControlsPrompt = iota
+	// Waiting for the key to bind
+	CapturePrompt
+	// The pressed key belongs to another action
+	ConflictPrompt
+	ResetControlsPrompt
+)
+
+// ControlsScene lists every action with the keys bound to it and lets the
+// player change them. It is driven by raw key presses rather than actions so
+// that it keeps working however badly the keys have been bound.
+type ControlsScene struct {
+	app *App
+
+	menuFocus int
+	scroll    int
+	// Rows available for actions when last drawn
+	visibleRows int
+	prompt      ControlsPrompt
+
+	// Keys waiting to be bound, and the action they're bound to right now
+	pending      []KeyBinding
+	conflictWith Action
+
+	// Result of the last change, shown in place of the help text
+	status string
+}
+
+func (cs *ControlsScene) Init(app *App) {
+	cs.app = app
+	cs.visibleRows = MIN_HEIGHT - CONTROLS_HEADER_ROWS
+}
+
+func (cs *ControlsScene) CapturingKeys() bool {
+	return cs.prompt != NoControlsPrompt
+}
+
+func (cs *ControlsScene) focusedAction() Action {
+	return Action(cs.menuFocus)
+}
+
+func (cs *ControlsScene) scrollToFocus() {
+	if cs.menuFocus < cs.scroll {
+		cs.scroll = cs.menuFocus
+	}
+	if cs.menuFocus >= cs.scroll+cs.visibleRows {
+		cs.scroll = cs.menuFocus - cs.visibleRows + 1
+	}
+	cs.scroll = max(0, cs.scroll)
+}
+
+func (cs *ControlsScene) bindings() map[Action][]KeyBinding {
+	return cs.app.Settings.KeyBindings
+}
+
+func (cs *ControlsScene) HandleEvent(ev tcell.Event) {
+	evt, ok := ev.(*tcell.EventKey)
+	if !ok {
+		return
+	}
+
+	switch cs.prompt {
+	case CapturePrompt:
+		cs.CaptureKey(evt)
+		return
+	case ConflictPrompt:
+		if IsRune(evt, 'y') || IsRune(evt, 'Y') {
+			cs.Bind(cs.pending)
+		} else {
+			cs.status = "Cancelled"
+		}
+		cs.prompt = NoControlsPrompt
+		return
+	case ResetControlsPrompt:
+		if IsRune(evt, 'y') || IsRune(evt, 'Y') {
+			cs.app.Settings.KeyBindings = DefaultSettings().KeyBindings
+			cs.SettingsChanged()
+			cs.status = "Restored the default controls"
+		}
+		cs.prompt = NoControlsPrompt
+		return
+	}
+
+	cs.status = ""
+	switch evt.Key() {
+	case tcell.KeyUp:
+		cs.menuFocus = max(0, cs.menuFocus-1)
+		cs.scrollToFocus()
+	case tcell.KeyDown:
+		cs.menuFocus = min(len(ActionNames)-1, cs.menuFocus+1)
+		cs.scrollToFocus()
+	case tcell.KeyEnter:
+		cs.prompt = CapturePrompt
+	case tcell.KeyBackspace, tcell.KeyBackspace2, tcell.KeyDelete:
+		act := cs.focusedAction()
+		if slices.Contains(ESSENTIAL_ACTIONS, act) {
+			cs.status = fmt.Sprintf("%v can't be left without a key", act.ToString())
+			return
+		}
+		cs.bindings()[act] = []KeyBinding{}
+		cs.SettingsChanged()
+	case tcell.KeyRune:
+		if evt.Rune() == 'D' {
+			cs.prompt = ResetControlsPrompt
+		}
+	}
+}
+
+func (cs *ControlsScene) HandleAction(act Action) {
+	if act == Quit && cs.prompt == NoControlsPrompt {
+		cs.app.OpenMenuScene()
+	}
+}
+
+// CaptureKey binds the pressed key to the focused action, asking first if it
+// is already used by another one. Letters are bound in both cases so that
+// caps lock doesn't get in the way.
+func (cs *ControlsScene) CaptureKey(ev *tcell.EventKey) {
+	kb := KeyBindingFromEvent(ev)
+	keys := []KeyBinding{kb}
+	if kb.Key == tcell.KeyRune && unicode.IsLetter(kb.Rune) {
+		keys = []KeyBinding{
+			RuneBinding(unicode.ToLower(kb.Rune)),
+			RuneBinding(unicode.ToUpper(kb.Rune)),
+		}
+	}
+
+	cs.prompt = NoControlsPrompt
+	if act, ok := cs.stranded(keys); ok {
+		cs.status = fmt.Sprintf(
+			"%v needs a key other than %v", act.ToString(), formatKeys(keys))
+		return
+	}
+	for _, key := range keys {
+		if act, ok := cs.boundTo(key); ok && act != cs.focusedAction() {
+			cs.pending = keys
+			cs.conflictWith = act
+			cs.prompt = ConflictPrompt
+			return
+		}
+	}
+	cs.Bind(keys)
+}
+
+// Returns the action the key is bound to, if any.
+func (cs *ControlsScene) boundTo(kb KeyBinding) (Action, bool) {
+	for act, keys := range cs.bindings() {
+		if slices.Contains(keys, kb) {
+			return act, true
+		}
+	}
+	return 0, false
+}
+
+// Returns an essential action other than the focused one that would be left
+// with no key if the keys were taken from it.
+func (cs *ControlsScene) stranded(keys []KeyBinding) (Action, bool) {
+	for _, act := range ESSENTIAL_ACTIONS {
+		bound := cs.bindings()[act]
+		if act == cs.focusedAction() || len(bound) == 0 {
+			continue
+		}
+		if !slices.ContainsFunc(bound, func(kb KeyBinding) bool {
+			return !slices.Contains(keys, kb)
+		}) {
+			return act, true
+		}
+	}
+	return 0, false
+}
+
+// Bind adds the keys to the focused action, taking them away from any other
+// action they were bound to.
+func (cs *ControlsScene) Bind(keys []KeyBinding) {
+	bindings := cs.bindings()
+	for act := range bindings {
+		bindings[act] = slices.DeleteFunc(bindings[act], func(kb KeyBinding) bool {
+			return slices.Contains(keys, kb)
+		})
+	}
+
+	act := cs.focusedAction()
+	bindings[act] = append(bindings[act], keys...)
+	cs.SettingsChanged()
+	cs.status = fmt.Sprintf("Bound %v to %v", formatKeys(keys), act.ToString())
+}
+
+func (cs *ControlsScene) SettingsChanged() {
+	cs.app.ApplySettings()
+	cs.app.SaveSettings()
+}
+
+func (cs *ControlsScene) Update() {
+}
+
+func formatKeys(keys []KeyBinding) string {
+	names := make([]string, len(keys))
+	for i, kb := range keys {
+		names[i] = kb.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func (cs *ControlsScene) Draw(sw, sh int, rr Area, lag float64) {
+	SetString(
+		rr.X,
+		rr.Y,
+		"Controls",
+		defStyle)
+
+	promptStyle := defStyle.Reverse(true)
+	switch cs.prompt {
+	case CapturePrompt:
+		SetString(
+			rr.X,
+			rr.Y+1,
+			fmt.Sprintf("Press a key for %v", cs.focusedAction().ToString()),
+			promptStyle,
+		)
+	case ConflictPrompt:
+		SetString(
+			rr.X,
+			rr.Y+1,
+			fmt.Sprintf(
+				"%v is bound to %v. Move it to %v? y/n",
+				formatKeys(cs.pending),
+				cs.conflictWith.ToString(),
+				cs.focusedAction().ToString(),
+			),
+			promptStyle,
+		)
+	case ResetControlsPrompt:
+		SetString(rr.X, rr.Y+1, "Restore the default controls? y/n", promptStyle)
+	default:
+		if cs.status != "" {
+			SetString(rr.X, rr.Y+1, cs.status, defStyle)
+		} else {
+			SetString(
+				rr.X,
+				rr.Y+1,
+				"enter:add key backspace:clear D:defaults",
+				defStyle.Dim(true),
+			)
+		}
+	}
+
+	header := defStyle.Underline(true)
+	SetString(rr.X+2, rr.Y+3, "Action", header)
+	SetString(rr.X+2+CONTROLS_ACTION_WIDTH, rr.Y+3, "Keys", header)
+
+	cs.visibleRows = rr.Height - CONTROLS_HEADER_ROWS
+	for row := 0; row < cs.visibleRows; row++ {
+		i := cs.scroll + row
+		if i >= len(ActionNames) {
+			break
+		}
+
+		act := Action(i)
+		name := ActionNames[i]
+		y := rr.Y + CONTROLS_HEADER_ROWS + row
+
+		style := defStyle
+		if i == cs.menuFocus {
+			style = style.Reverse(true)
+			Screen.SetContent(rr.X, y, '*', nil, defStyle)
+		}
+		SetString(rr.X+2, y, name, style)
+
+		keys := cs.bindings()[act]
+		if len(keys) == 0 {
+			keyStyle := defStyle.Dim(true)
+			if slices.Contains(ESSENTIAL_ACTIONS, act) {
+				keyStyle = defStyle.Foreground(tcell.ColorRed)
+			}
+			SetString(rr.X+2+CONTROLS_ACTION_WIDTH, y, "(none)", keyStyle)
+		} else {
+			SetString(rr.X+2+CONTROLS_ACTION_WIDTH, y, formatKeys(keys), defStyle)
+		}
+	}
+
+	// Scroll indicators
+	if cs.scroll > 0 {
+		Screen.SetContent(rr.Right()-1, rr.Y+CONTROLS_HEADER_ROWS, '^', nil, defStyle)
+	}
+	if cs.scroll+cs.visibleRows < len(ActionNames) {
+		Screen.SetContent(rr.Right()-1, rr.Bottom()-1, 'v', nil, defStyle)
+	}
+}
+
+func (cs *ControlsScene) Cleanup() {
+}
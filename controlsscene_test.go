@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"log"
+	"slices"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func newControlsScene(t *testing.T) *ControlsScene {
+	tempSettingsPath(t)
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := &ControlsScene{}
+	cs.Init(&App{
+		Logger:   log.New(io.Discard, "", 0),
+		Audio:    &NullAudioEngine{},
+		Settings: settings,
+	})
+	return cs
+}
+
+func pressKey(cs *ControlsScene, key tcell.Key, r rune) {
+	cs.HandleEvent(tcell.NewEventKey(key, r, tcell.ModNone))
+}
+
+func TestControlsKeepEssentialKeys(t *testing.T) {
+	for _, act := range ESSENTIAL_ACTIONS {
+		cs := newControlsScene(t)
+		cs.menuFocus = int(act)
+		pressKey(cs, tcell.KeyBackspace2, 0)
+		if !slices.Equal(cs.bindings()[act], DefaultKeyBindings[act]) {
+			t.Errorf("Cleared %v", act.ToString())
+		}
+	}
+
+	cs := newControlsScene(t)
+	cs.menuFocus = int(MoveLeft)
+	pressKey(cs, tcell.KeyBackspace2, 0)
+	if len(cs.bindings()[MoveLeft]) != 0 {
+		t.Errorf("Couldn't clear Move Left: %v", cs.bindings()[MoveLeft])
+	}
+
+	for _, tc := range []struct {
+		key tcell.Key
+		r   rune
+		act Action
+	}{
+		{tcell.KeyEnter, 0, MenuConfirm},
+		{tcell.KeyUp, 0, MoveUp},
+		// Both cases of a letter are taken at once
+		{tcell.KeyRune, 'q', Quit},
+	} {
+		cs := newControlsScene(t)
+		cs.menuFocus = int(HardDrop)
+		pressKey(cs, tcell.KeyEnter, 0)
+		pressKey(cs, tc.key, tc.r)
+		if cs.prompt != NoControlsPrompt ||
+			!slices.Equal(cs.bindings()[tc.act], DefaultKeyBindings[tc.act]) {
+			t.Errorf("Took the only key from %v", tc.act.ToString())
+		}
+	}
+
+	// A second key frees up the first
+	cs = newControlsScene(t)
+	cs.bindings()[MoveUp] = append(cs.bindings()[MoveUp], RuneBinding('^'))
+	cs.menuFocus = int(HardDrop)
+	pressKey(cs, tcell.KeyEnter, 0)
+	pressKey(cs, tcell.KeyUp, 0)
+	if cs.prompt != ConflictPrompt {
+		t.Fatalf("No conflict prompt for a spare key")
+	}
+	pressKey(cs, tcell.KeyRune, 'y')
+	if !slices.Equal(cs.bindings()[MoveUp], []KeyBinding{RuneBinding('^')}) {
+		t.Errorf("Move Up left with %v", cs.bindings()[MoveUp])
+	}
+}
+
+func TestControlsSceneScrolls(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	screen.SetSize(MIN_WIDTH, MIN_HEIGHT)
+	Screen = screen
+	rr := Area{Width: MIN_WIDTH, Height: MIN_HEIGHT}
+
+	cs := newControlsScene(t)
+	for i := 0; i < len(ActionNames); i++ {
+		screen.Clear()
+		cs.Draw(MIN_WIDTH, MIN_HEIGHT, rr, 0)
+		screen.Show()
+
+		cells, width, _ := screen.GetContents()
+		focused := -1
+		for y := CONTROLS_HEADER_ROWS; y < MIN_HEIGHT; y++ {
+			if slices.Equal(cells[y*width].Runes, []rune{'*'}) {
+				focused = y
+			}
+		}
+		if focused < 0 {
+			t.Errorf("%v is off screen", cs.focusedAction().ToString())
+		}
+		pressKey(cs, tcell.KeyDown, 0)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io/fs"
+	"math/rand"
+	"os"
+	"slices"
+	"time"
+)
+
+// Stores the results of past daily challenges alongside the replays. Hidden
+// so it isn't listed as a replay itself.
+const DAILY_FILE = ".daily.json"
+
+const DAILY_DATE_FORMAT = "2006-01-02"
+
+// Objectives a daily challenge can be. Changing this list changes every
+// challenge from then on, so entries should only ever be added at the end.
+var DAILY_VARIANTS = []struct {
+	ID  ObjectiveID
+	New func() ObjectiveSettings
+}{
+	{LineClear, func() ObjectiveSettings {
+		return &LineClearSettings{Lines: 40}
+	}},
+	{LineClear, func() ObjectiveSettings {
+		return &LineClearSettings{Lines: 20}
+	}},
+	{Cheese, func() ObjectiveSettings {
+		return &CheeseSettings{Garbage: 18}
+	}},
+	{ScoreAttack, func() ObjectiveSettings {
+		return &ScoreAttackSettings{Duration: 120}
+	}},
+	{Survival, func() ObjectiveSettings {
+		return &SurvivalSettings{GarbageRate: 1000}
+	}},
+	{TSpinDrill, func() ObjectiveSettings {
+		return &TSpinDrillSettings{Rounds: 10, Doubles: true, Triples: true}
+	}},
+	{PCTraining, func() ObjectiveSettings {
+		return &PCTrainingSettings{PerfectClears: 3}
+	}},
+}
+
+// DailyChallenge is a game that everyone playing on the same day gets: the
+// same objective, settings and pieces.
+type DailyChallenge struct {
+	Date              string
+	Seed              int64
+	ObjectiveID       ObjectiveID
+	ObjectiveSettings ObjectiveSettings
+	TetrisSettings    GlobalTetrisSettings
+}
+
+// DailyChallengeFor works out the challenge for the day of the given time.
+// Days are taken in UTC, so players in different time zones share them.
+func DailyChallengeFor(t time.Time) DailyChallenge {
+	date := t.UTC().Format(DAILY_DATE_FORMAT)
+
+	hash := fnv.New64a()
+	hash.Write([]byte(date))
+	seed := int64(hash.Sum64())
+
+	rng := rand.New(rand.NewSource(seed))
+	variant := DAILY_VARIANTS[rng.Intn(len(DAILY_VARIANTS))]
+
+	return DailyChallenge{
+		Date:              date,
+		Seed:              seed,
+		ObjectiveID:       variant.ID,
+		ObjectiveSettings: variant.New(),
+		TetrisSettings:    DefaultTetrisSettings,
+	}
+}
+
+// Category names the challenge's objective and settings, as in the records.
+func (dc DailyChallenge) Category() string {
+	return ObjectiveCategory(dc.ObjectiveID, dc.ObjectiveSettings)
+}
+
+// Matches reports whether a replay is a run of this challenge, whoever
+// played it.
+func (dc DailyChallenge) Matches(rd *ReplayData) bool {
+	return rd.Seed == dc.Seed &&
+		rd.Board == nil &&
+		rd.ObjectiveID == dc.ObjectiveID &&
+		rd.TetrisSettings == dc.TetrisSettings &&
+		ObjectiveCategory(rd.ObjectiveID, rd.ObjectiveSettings) == dc.Category()
+}
+
+// DailyRun is a finished run of a daily challenge found among the replays.
+type DailyRun struct {
+	Replay  string
+	Summary ReplaySummary
+}
+
+// FindRuns looks through the replays for runs of the challenge, including
+// ones shared by other players, best first. Only replays whose seed,
+// objective and settings match are played through. It gives up once done is
+// closed.
+func (dc DailyChallenge) FindRuns(done <-chan struct{}) ([]DailyRun, error) {
+	entries, err := ListReplays()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []DailyRun
+	for _, entry := range entries {
+		select {
+		case <-done:
+			return nil, nil
+		default:
+		}
+
+		replayData, err := ReadReplayFile(entry.Name)
+		if err != nil || !dc.Matches(replayData) {
+			continue
+		}
+		runs = append(runs, DailyRun{
+			Replay:  entry.Name,
+			Summary: replayData.Simulate().Summary(),
+		})
+	}
+
+	ot, _ := GetObjectiveType(dc.ObjectiveID)
+	slices.SortStableFunc(runs, func(a, b DailyRun) int {
+		switch {
+		case ot.Better(a.Summary, b.Summary):
+			return -1
+		case ot.Better(b.Summary, a.Summary):
+			return 1
+		}
+		return 0
+	})
+	return runs, nil
+}
+
+// DailyResult is how the player did on one day's challenge.
+type DailyResult struct {
+	Attempts int
+	// Best finished game, if any
+	Best *Record
+}
+
+// DailyBook holds the player's results for every day they played, by date.
+type DailyBook struct {
+	Days map[string]*DailyResult
+}
+
+func LoadDailyBook() (*DailyBook, error) {
+	db := &DailyBook{
+		Days: make(map[string]*DailyResult),
+	}
+
+	data, err := os.ReadFile(replayPath(DAILY_FILE))
+	if errors.Is(err, fs.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return db, err
+	}
+
+	err = json.Unmarshal(data, db)
+	if db.Days == nil {
+		db.Days = make(map[string]*DailyResult)
+	}
+	return db, err
+}
+
+func (db *DailyBook) Save() error {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(REPLAY_DIR, 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(replayPath(DAILY_FILE), data, 0644)
+}
+
+// Result returns the results for a day, which are empty if it wasn't played.
+func (db *DailyBook) Result(date string) DailyResult {
+	if result, ok := db.Days[date]; ok {
+		return *result
+	}
+	return DailyResult{}
+}
+
+// Add counts an attempt at a day's challenge, and keeps it if it's the best
+// one so far. It returns whether it was.
+func (db *DailyBook) Add(date string, rec Record) bool {
+	result, ok := db.Days[date]
+	if !ok {
+		result = &DailyResult{}
+		db.Days[date] = result
+	}
+	result.Attempts++
+
+	ot, ok := GetObjectiveType(rec.Summary.ObjectiveID)
+	if !ok {
+		return false
+	}
+	if result.Best != nil && !ot.Better(rec.Summary, result.Best.Summary) {
+		return false
+	}
+	result.Best = &rec
+	return true
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDailyChallengeFor(t *testing.T) {
+	morning := time.Date(2024, 3, 5, 1, 0, 0, 0, time.UTC)
+	challenge := DailyChallengeFor(morning)
+	if challenge.Date != "2024-03-05" {
+		t.Errorf("Challenge for %v is dated %v", morning, challenge.Date)
+	}
+
+	// The same UTC day, seen from either side of the date line
+	for _, when := range []time.Time{
+		morning.Add(22 * time.Hour),
+		morning.In(time.FixedZone("UTC-8", -8*60*60)),
+		morning.Add(12 * time.Hour).In(time.FixedZone("UTC+13", 13*60*60)),
+	} {
+		if other := DailyChallengeFor(when); !reflect.DeepEqual(other, challenge) {
+			t.Errorf("Challenge for %v is %+v, want %+v", when, other, challenge)
+		}
+	}
+
+	if next := DailyChallengeFor(morning.Add(24 * time.Hour)); next.Seed == challenge.Seed {
+		t.Errorf("Next day has the same seed")
+	}
+}
+
+// Finds a day whose challenge is a sprint, so its settings can be changed
+// knowingly.
+func sprintChallenge(t *testing.T) DailyChallenge {
+	day := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		challenge := DailyChallengeFor(day.AddDate(0, 0, i))
+		if challenge.ObjectiveID == LineClear {
+			return challenge
+		}
+	}
+	t.Fatal("No sprint in 100 days")
+	return DailyChallenge{}
+}
+
+func dailyRun(challenge DailyChallenge) *ReplayData {
+	return &ReplayData{
+		Seed:              challenge.Seed,
+		TetrisSettings:    challenge.TetrisSettings,
+		ObjectiveID:       challenge.ObjectiveID,
+		ObjectiveSettings: challenge.ObjectiveSettings,
+	}
+}
+
+func TestDailyChallengeMatches(t *testing.T) {
+	challenge := sprintChallenge(t)
+	if !challenge.Matches(dailyRun(challenge)) {
+		t.Errorf("A run of the challenge doesn't match")
+	}
+
+	for name, change := range map[string]func(rd *ReplayData){
+		"seed":          func(rd *ReplayData) { rd.Seed++ },
+		"board":         func(rd *ReplayData) { rd.Board = NewBoard() },
+		"game settings": func(rd *ReplayData) { rd.TetrisSettings.LockDelay++ },
+		"line target":   func(rd *ReplayData) { rd.ObjectiveSettings = &LineClearSettings{Lines: 1} },
+		"objective": func(rd *ReplayData) {
+			rd.ObjectiveID = Endless
+			rd.ObjectiveSettings = &EndlessSettings{}
+		},
+	} {
+		rd := dailyRun(challenge)
+		change(rd)
+		if challenge.Matches(rd) {
+			t.Errorf("A run with a different %v matches", name)
+		}
+	}
+}
+
+func TestDailyChallengeFindRuns(t *testing.T) {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	defer os.Chdir(wd)
+
+	challenge := sprintChallenge(t)
+	other := dailyRun(challenge)
+	other.Seed++
+	var names []string
+	for _, rd := range []*ReplayData{dailyRun(challenge), other} {
+		name, err := SaveReplay(rd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	runs, err := challenge.FindRuns(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Replay != names[0] {
+		t.Errorf("Found runs %+v", runs)
+	}
+
+	done := make(chan struct{})
+	close(done)
+	if runs, _ := challenge.FindRuns(done); len(runs) != 0 {
+		t.Errorf("Found %d runs after being stopped", len(runs))
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Width of the column showing each run's result
+const DAILY_RESULT_WIDTH = 16
+
+// Rows above the list of runs
+const DAILY_HEADER_ROWS = 9
+
+// DailyScene shows today's challenge, how the player has done at it, and the
+// runs of it found among the replays, including ones shared by others.
+type DailyScene struct {
+	app *App
+
+	challenge DailyChallenge
+	result    DailyResult
+	runs      []DailyRun
+	// Receives the runs once the replays have been searched. Nil unless a
+	// search is under way.
+	found chan dailySearch
+	// Closed to stop the search when the scene is left, after which it's
+	// nil until the next search
+	done chan struct{}
+
+	// Focus 0 is the play option, and the rest are the runs
+	focus  int
+	scroll int
+	// Rows available for runs when last drawn
+	visibleRows int
+	// Set when the scene is left to play or watch, so everything is read
+	// again once the player comes back
+	stale bool
+}
+
+type dailySearch struct {
+	runs []DailyRun
+	err  error
+}
+
+func (ds *DailyScene) Init(app *App) {
+	ds.app = app
+	ds.visibleRows = MIN_HEIGHT - DAILY_HEADER_ROWS
+	ds.Load()
+}
+
+// Load works out today's challenge and reads the results of it. Runs are
+// searched for in the background, since each one has to be played through.
+func (ds *DailyScene) Load() {
+	ds.challenge = DailyChallengeFor(time.Now())
+
+	book, err := LoadDailyBook()
+	if err != nil {
+		ds.app.ReportError("Could not load daily results", err)
+	}
+	ds.result = book.Result(ds.challenge.Date)
+
+	ds.found = make(chan dailySearch, 1)
+	ds.done = make(chan struct{})
+	go ds.findRuns(ds.challenge, ds.found, ds.done)
+}
+
+func (ds *DailyScene) findRuns(
+	challenge DailyChallenge,
+	found chan<- dailySearch,
+	done <-chan struct{},
+) {
+	runs, err := challenge.FindRuns(done)
+	found <- dailySearch{runs, err}
+}
+
+func (ds *DailyScene) scrollToFocus() {
+	run := ds.focus - 1
+	if run < ds.scroll {
+		ds.scroll = run
+	}
+	if run >= ds.scroll+ds.visibleRows {
+		ds.scroll = run - ds.visibleRows + 1
+	}
+	ds.scroll = max(0, ds.scroll)
+}
+
+func (ds *DailyScene) HandleEvent(ev tcell.Event) {
+}
+
+func (ds *DailyScene) HandleAction(act Action) {
+	switch act {
+	case MoveUp:
+		ds.focus = max(0, ds.focus-1)
+		ds.scrollToFocus()
+	case MoveDown:
+		ds.focus = min(len(ds.runs), ds.focus+1)
+		ds.scrollToFocus()
+	case MenuConfirm:
+		if ds.focus == 0 {
+			ds.Play()
+		} else {
+			ds.Watch(ds.runs[ds.focus-1])
+		}
+	case Quit:
+		ds.app.OpenMenuScene()
+	}
+}
+
+// Play starts an attempt at today's challenge.
+func (ds *DailyScene) Play() {
+	ds.app.OpenGameScene(
+		ds.challenge.TetrisSettings,
+		ds.challenge.ObjectiveID,
+		ds.challenge.ObjectiveSettings,
+		WithDaily(ds.challenge),
+		WithBack(ds),
+	)
+}
+
+func (ds *DailyScene) Watch(run DailyRun) {
+	replayData, err := ReadReplayFile(run.Replay)
+	if err != nil {
+		ds.app.ReportError("Could not load replay", err)
+		return
+	}
+	ds.app.OpenReplayViewerScene(*replayData, ds)
+}
+
+func (ds *DailyScene) Update() {
+	if ds.stale {
+		ds.stale = false
+		ds.Load()
+	}
+
+	if ds.found == nil {
+		return
+	}
+	select {
+	case search := <-ds.found:
+		ds.found = nil
+		if search.err != nil {
+			ds.app.ReportError("Could not list replays", search.err)
+		}
+		ds.runs = search.runs
+		ds.focus = min(ds.focus, len(ds.runs))
+		ds.scrollToFocus()
+	default:
+	}
+}
+
+func (ds *DailyScene) Draw(sw, sh int, rr Area, lag float64) {
+	ot, _ := GetObjectiveType(ds.challenge.ObjectiveID)
+
+	SetString(rr.X, rr.Y, fmt.Sprintf("Daily challenge %v", ds.challenge.Date), defStyle)
+	SetString(rr.X, rr.Y+1, ds.challenge.Category(), defStyle.Bold(true))
+	SetString(
+		rr.X,
+		rr.Y+2,
+		"Same pieces for everyone today  enter:play/watch q:back",
+		defStyle.Dim(true),
+	)
+
+	best := "none yet"
+	if ds.result.Best != nil {
+		best = FormatResult(ot, ds.result.Best.Summary)
+	}
+	SetString(
+		rr.X,
+		rr.Y+4,
+		fmt.Sprintf("Attempts today: %d  Best: %v", ds.result.Attempts, best),
+		defStyle,
+	)
+
+	playStyle := defStyle
+	if ds.focus == 0 {
+		playStyle = playStyle.Reverse(true)
+		Screen.SetContent(rr.X, rr.Y+6, '*', nil, defStyle)
+	}
+	SetString(rr.X+2, rr.Y+6, "Play", playStyle)
+
+	header := defStyle.Underline(true)
+	SetString(rr.X+2, rr.Y+DAILY_HEADER_ROWS-1, "Runs", header)
+	// Runs found before stay up until the search finishes
+	if ds.found != nil {
+		SetString(rr.X+7, rr.Y+DAILY_HEADER_ROWS-1, "searching replays...", defStyle.Dim(true))
+	} else if len(ds.runs) == 0 {
+		SetString(
+			rr.X+2,
+			rr.Y+DAILY_HEADER_ROWS,
+			fmt.Sprintf("No runs in %v/ yet", REPLAY_DIR),
+			defStyle.Dim(true),
+		)
+		return
+	}
+
+	ds.visibleRows = rr.Height - DAILY_HEADER_ROWS
+	for row := 0; row < ds.visibleRows; row++ {
+		i := ds.scroll + row
+		if i >= len(ds.runs) {
+			break
+		}
+
+		y := rr.Y + DAILY_HEADER_ROWS + row
+		run := ds.runs[i]
+		style := defStyle
+		if i+1 == ds.focus {
+			style = style.Reverse(true)
+			Screen.SetContent(rr.X, y, '*', nil, defStyle)
+		}
+		SetString(
+			rr.X+2,
+			y,
+			fmt.Sprintf(
+				"%3d. %-*v%v",
+				i+1,
+				DAILY_RESULT_WIDTH,
+				FormatResult(ot, run.Summary),
+				run.Replay,
+			),
+			style,
+		)
+	}
+
+	// Scroll indicators
+	if ds.scroll > 0 {
+		Screen.SetContent(rr.Right()-1, rr.Y+DAILY_HEADER_ROWS, '^', nil, defStyle)
+	}
+	if ds.scroll+ds.visibleRows < len(ds.runs) {
+		Screen.SetContent(rr.Right()-1, rr.Bottom()-1, 'v', nil, defStyle)
+	}
+}
+
+// The scene can be left again before Update has reloaded it, so the search
+// is only stopped once.
+func (ds *DailyScene) Cleanup() {
+	ds.stale = true
+	if ds.done != nil {
+		close(ds.done)
+		ds.done = nil
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestDailySceneLeftTwice(t *testing.T) {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	defer os.Chdir(wd)
+
+	ds := &DailyScene{}
+	ds.Init(&App{Logger: log.New(io.Discard, "", 0)})
+
+	// Left to play, then left again before the scene updated
+	ds.Cleanup()
+	ds.Cleanup()
+
+	ds.Update()
+	if ds.done == nil || ds.stale {
+		t.Errorf("Scene wasn't reloaded after being left")
+	}
+	ds.Cleanup()
+}
@@ -222,6 +222,81 @@ func (nf *IntegerField) Draw(x, y int, editing bool) {
 	}
 }
 
+type TextField struct {
+	Value     string
+	MaxLength int
+	Cursor    int
+	OnChange  func(value string)
+}
+
+func (tf *TextField) HandleInput(evt tcell.Event) {
+	switch evt := evt.(type) {
+	case *tcell.EventKey:
+		runeArr := []rune(tf.Value)
+		switch evt.Key() {
+		case tcell.KeyRune:
+			if tf.MaxLength > 0 && len(runeArr) >= tf.MaxLength {
+				return
+			}
+			tf.Value = strings.Join(
+				[]string{
+					string(runeArr[:tf.Cursor]),
+					string(evt.Rune()),
+					string(runeArr[tf.Cursor:]),
+				}, "",
+			)
+			tf.Cursor++
+			tf.OnChange(tf.Value)
+		case tcell.KeyLeft:
+			tf.Cursor = max(0, tf.Cursor-1)
+		case tcell.KeyRight:
+			tf.Cursor = min(len(runeArr), tf.Cursor+1)
+		case tcell.KeyHome:
+			tf.Cursor = 0
+		case tcell.KeyEnd:
+			tf.Cursor = len(runeArr)
+		case tcell.KeyBackspace:
+			fallthrough
+		case tcell.KeyBackspace2:
+			if tf.Cursor == 0 {
+				return
+			}
+			tf.Value = string(runeArr[:tf.Cursor-1]) +
+				string(runeArr[tf.Cursor:])
+			tf.Cursor--
+			tf.OnChange(tf.Value)
+		case tcell.KeyDelete:
+			if tf.Cursor == len(runeArr) {
+				return
+			}
+			tf.Value = string(runeArr[:tf.Cursor]) +
+				string(runeArr[tf.Cursor+1:])
+			tf.OnChange(tf.Value)
+		}
+	}
+}
+
+func (tf *TextField) HandleAction(act Action) {
+}
+
+func (tf *TextField) Draw(x, y int, editing bool) {
+	SetString(
+		x, y,
+		tf.Value,
+		defStyle,
+	)
+
+	if editing {
+		cursorX := x + runewidth.StringWidth(string([]rune(tf.Value)[:tf.Cursor]))
+		cell, cm, style, _ := Screen.GetContent(cursorX, y)
+		Screen.SetContent(
+			cursorX,
+			y,
+			cell, cm, style.Reverse(true),
+		)
+	}
+}
+
 func NewBooleanField(
 	name string,
 	value bool,
@@ -258,3 +333,17 @@ func NewIntegerField(
 		Field: field,
 	}
 }
+
+func NewTextField(
+	name string, value string, maxLength int, onChange func(value string),
+) FormField {
+	return FormField{
+		Name: name,
+		Field: &TextField{
+			Value:     value,
+			MaxLength: maxLength,
+			Cursor:    len([]rune(value)),
+			OnChange:  onChange,
+		},
+	}
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// Longest queue that can be typed in for a board
+const EDITOR_QUEUE_MAX_LENGTH = 40
+
+type EditorPrompt int8
+
+const (
+	NoEditorPrompt EditorPrompt = iota
+	QueuePrompt
+	SaveBoardPrompt
+	LoadBoardPrompt
+	FumenPrompt
+)
+
+// EditorScene lets the player set up a board, its hold piece and the start
+// of its queue, then play any objective from it. Cells are painted with the
+// mouse or a keyboard cursor. Like the controls scene, it is driven by raw
+// key presses, since nearly every key means something here.
+type EditorScene struct {
+	app *App
+
+	board *Board
+	// Name the board was last saved or loaded under
+	name string
+
+	cursorX, cursorY int
+	// Cell value painted by the cursor and the left mouse button
+	palette int
+	// Index into MenuObjectiveTypes of the objective to play
+	objectiveIdx int
+
+	prompt      EditorPrompt
+	promptField FormField
+	promptValue string
+	// Saved boards listed by the load prompt, and the focused one
+	boardNames []string
+	loadFocus  int
+
+	// Where the board was last drawn, for finding the cell under the mouse
+	boardArea Area
+
+	// Result of the last change, shown in place of the help text
+	status string
+}
+
+func (es *EditorScene) Init(app *App) {
+	es.app = app
+	es.board = NewBoard()
+	es.cursorX = BOARD_WIDTH / 2
+	es.cursorY = BOARD_HEIGHT - 1
+	es.palette = GARBAGE_CELL
+}
+
+func (es *EditorScene) CapturingKeys() bool {
+	return es.prompt != NoEditorPrompt
+}
+
+func (es *EditorScene) HandleEvent(ev tcell.Event) {
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		if es.prompt != NoEditorPrompt {
+			es.HandlePromptKey(ev)
+			return
+		}
+		es.status = ""
+		es.HandleKey(ev)
+	case *tcell.EventMouse:
+		if es.prompt != NoEditorPrompt {
+			return
+		}
+		x, y := ev.Position()
+		if !es.boardArea.Contains(x, y) {
+			return
+		}
+		x, y = x-es.boardArea.X, y-es.boardArea.Y
+		switch {
+		case ev.Buttons()&tcell.Button1 != 0:
+			es.cursorX, es.cursorY = x, y
+			es.board.Grid.Set(x, y, es.palette)
+		case ev.Buttons()&(tcell.Button2|tcell.Button3) != 0:
+			es.cursorX, es.cursorY = x, y
+			es.board.Grid.Set(x, y, 0)
+		}
+	}
+}
+
+func (es *EditorScene) HandleKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		es.cursorY = max(0, es.cursorY-1)
+	case tcell.KeyDown:
+		es.cursorY = min(BOARD_HEIGHT-1, es.cursorY+1)
+	case tcell.KeyLeft:
+		es.cursorX = max(0, es.cursorX-1)
+	case tcell.KeyRight:
+		es.cursorX = min(BOARD_WIDTH-1, es.cursorX+1)
+	case tcell.KeyEnter:
+		es.board.Grid.Set(es.cursorX, es.cursorY, es.palette)
+	case tcell.KeyBackspace, tcell.KeyBackspace2, tcell.KeyDelete:
+		es.board.Grid.Set(es.cursorX, es.cursorY, 0)
+	case tcell.KeyRune:
+		switch r := ev.Rune(); {
+		case r == ' ':
+			es.board.Grid.Set(es.cursorX, es.cursorY, es.palette)
+		case r >= '0' && r <= '0'+GARBAGE_CELL:
+			es.palette = int(r - '0')
+		case r == 'h':
+			es.CycleHold()
+		case r == 'e':
+			es.OpenQueuePrompt()
+		case r == 'G':
+			es.board.AddGarbageRow(es.cursorX)
+		case r == 'C':
+			es.board = NewBoard()
+			es.status = "Cleared the board"
+		case r == 'o':
+			es.objectiveIdx = (es.objectiveIdx + 1) % len(MenuObjectiveTypes())
+		case r == 'O':
+			count := len(MenuObjectiveTypes())
+			es.objectiveIdx = (es.objectiveIdx + count - 1) % count
+		case r == 'p':
+			es.Play()
+		case r == 'w':
+			es.OpenSavePrompt()
+		case r == 'l':
+			es.OpenLoadPrompt()
+		case r == 'i':
+			es.OpenFumenPrompt()
+		case r == 'f':
+			es.ExportFumen()
+		}
+	}
+}
+
+func (es *EditorScene) HandlePromptKey(ev *tcell.EventKey) {
+	if ev.Key() == tcell.KeyCtrlQ || ev.Key() == tcell.KeyEscape {
+		es.prompt = NoEditorPrompt
+		return
+	}
+
+	switch es.prompt {
+	case QueuePrompt, SaveBoardPrompt, FumenPrompt:
+		if ev.Key() == tcell.KeyEnter {
+			es.ConfirmPrompt()
+			return
+		}
+		es.promptField.Field.HandleInput(ev)
+	case LoadBoardPrompt:
+		switch ev.Key() {
+		case tcell.KeyUp:
+			es.loadFocus = max(0, es.loadFocus-1)
+		case tcell.KeyDown:
+			es.loadFocus = min(len(es.boardNames)-1, es.loadFocus+1)
+		case tcell.KeyEnter:
+			es.ConfirmPrompt()
+		}
+	}
+}
+
+func (es *EditorScene) HandleAction(act Action) {
+	if act == Quit && es.prompt == NoEditorPrompt {
+		es.app.OpenMenuScene()
+	}
+}
+
+// CycleHold steps the hold piece through every piece and back to none.
+func (es *EditorScene) CycleHold() {
+	switch {
+	case es.board.Hold == NO_HOLD_PIECE:
+		es.board.Hold = 0
+	case es.board.Hold+1 < len(Pieces):
+		es.board.Hold++
+	default:
+		es.board.Hold = NO_HOLD_PIECE
+	}
+}
+
+func (es *EditorScene) OpenQueuePrompt() {
+	es.prompt = QueuePrompt
+	es.promptValue = FormatQueue(es.board.Queue)
+	es.promptField = NewTextField(
+		"Queue",
+		es.promptValue,
+		EDITOR_QUEUE_MAX_LENGTH,
+		func(value string) {
+			es.promptValue = value
+		},
+	)
+}
+
+func (es *EditorScene) OpenSavePrompt() {
+	es.prompt = SaveBoardPrompt
+	es.promptValue = es.name
+	es.promptField = NewTextField(
+		"Save as",
+		es.promptValue,
+		REPLAY_NAME_MAX_LENGTH,
+		func(value string) {
+			es.promptValue = value
+		},
+	)
+}
+
+// OpenFumenPrompt asks for a Fumen to replace the board with. Fumen strings
+// are long, so they're meant to be pasted in.
+func (es *EditorScene) OpenFumenPrompt() {
+	es.prompt = FumenPrompt
+	es.promptValue = ""
+	es.promptField = NewTextField(
+		"Fumen",
+		"",
+		0,
+		func(value string) {
+			es.promptValue = value
+		},
+	)
+}
+
+// ExportFumen writes the board to a file as a Fumen, since the terminal
+// can't be relied on to copy it.
+func (es *EditorScene) ExportFumen() {
+	name := es.name
+	if name == "" {
+		name = "board"
+	}
+
+	path, err := ExportFumenFile(name, EncodeFumen([]FumenPage{es.board.FumenPage()}))
+	if err != nil {
+		es.app.ReportError("Could not export fumen", err)
+		return
+	}
+	es.status = fmt.Sprintf("Wrote %v", path)
+}
+
+func (es *EditorScene) OpenLoadPrompt() {
+	names, err := ListBoards()
+	if err != nil {
+		es.app.ReportError("Could not list boards", err)
+		return
+	}
+	if len(names) == 0 {
+		es.status = "No saved boards"
+		return
+	}
+
+	es.prompt = LoadBoardPrompt
+	es.boardNames = names
+	es.loadFocus = 0
+}
+
+// Carries out what the open prompt is asking for.
+func (es *EditorScene) ConfirmPrompt() {
+	prompt := es.prompt
+	es.prompt = NoEditorPrompt
+
+	switch prompt {
+	case QueuePrompt:
+		queue, err := ParseQueue(es.promptValue)
+		if err != nil {
+			es.status = err.Error()
+			return
+		}
+		es.board.Queue = queue
+	case SaveBoardPrompt:
+		err := SaveBoardFile(es.promptValue, es.board)
+		if err != nil {
+			es.app.ReportError("Could not save board", err)
+			return
+		}
+		es.name = es.promptValue
+		es.status = fmt.Sprintf("Saved as %v", es.name)
+	case LoadBoardPrompt:
+		name := es.boardNames[es.loadFocus]
+		board, err := ReadBoardFile(name)
+		if err != nil {
+			es.app.ReportError(fmt.Sprintf("Could not load %v", name), err)
+			return
+		}
+		es.board = board
+		es.name = name
+		es.status = fmt.Sprintf("Loaded %v", name)
+	case FumenPrompt:
+		pages, err := DecodeFumen(es.promptValue)
+		if err != nil {
+			es.status = err.Error()
+			return
+		}
+		board, err := BoardFromFumen(pages, 0)
+		if err != nil {
+			es.status = err.Error()
+			return
+		}
+		es.board = board
+		es.status = fmt.Sprintf("Imported page 1 of %v", len(pages))
+	}
+}
+
+// Play starts the chosen objective from a copy of the board, coming back to
+// the editor afterwards.
+func (es *EditorScene) Play() {
+	ot := MenuObjectiveTypes()[es.objectiveIdx]
+	es.app.OpenGameScene(
+		es.app.Settings.TetrisSettingsFor(ot.ID),
+		ot.ID,
+		es.app.Settings.ObjectiveSettingsFor(ot),
+		WithBoard(es.board.Clone()),
+		WithBack(es),
+	)
+}
+
+func (es *EditorScene) Update() {
+}
+
+// Returns the style a cell value is drawn with.
+func editorCellStyle(cell int) tcell.Style {
+	if cell == 0 {
+		return defStyle.Dim(true)
+	}
+	return SolidPieceStyle(cell - 1)
+}
+
+func editorCellName(cell int) string {
+	switch cell {
+	case 0:
+		return "empty"
+	case GARBAGE_CELL:
+		return "garbage"
+	default:
+		return string(PIECE_LETTERS[cell-1])
+	}
+}
+
+func (es *EditorScene) Draw(sw, sh int, rr Area, lag float64) {
+	SetString(rr.X, rr.Y, "Board editor", defStyle)
+	if es.name != "" {
+		SetString(rr.X+14, rr.Y, es.name, defStyle.Bold(true))
+	}
+
+	es.boardArea = Area{
+		X:      rr.X + 2,
+		Y:      rr.Y + 2,
+		Width:  BOARD_WIDTH,
+		Height: BOARD_HEIGHT,
+	}
+	es.DrawBoard(es.boardArea)
+
+	x := es.boardArea.Right() + 3
+	y := rr.Y + 2
+	SetString(x, y, "Paint", defStyle.Underline(true))
+	for cell := 0; cell <= GARBAGE_CELL; cell++ {
+		cx := x + 2*cell
+		if cell == es.palette {
+			Screen.SetContent(cx, y+2, '^', nil, defStyle)
+		}
+		Screen.SetContent(cx, y+1, rune('0'+cell), nil, editorCellStyle(cell))
+	}
+	SetString(x+2*(GARBAGE_CELL+1), y+1, editorCellName(es.palette), defStyle)
+
+	hold := "none"
+	if es.board.Hold != NO_HOLD_PIECE {
+		hold = string(PIECE_LETTERS[es.board.Hold])
+	}
+	queue := FormatQueue(es.board.Queue)
+	if queue == "" {
+		queue = "random"
+	}
+	drawLabeledValues(x, y+4, [][2]string{
+		{"Hold", hold},
+		{"Queue", queue},
+		{"Objective", MenuObjectiveTypes()[es.objectiveIdx].Name},
+	})
+
+	help := []string{
+		"arrows/mouse:move",
+		"space:paint del:erase",
+		"0-8:paint with",
+		"right click:erase",
+		"h:hold e:queue",
+		"G:garbage row C:clear",
+		"o/O:objective p:play",
+		"w:save l:load q:back",
+		"i/f:fumen in/out",
+	}
+	if es.prompt != LoadBoardPrompt {
+		for i, line := range help {
+			SetString(x, y+8+i, line, defStyle.Dim(true))
+		}
+	}
+
+	es.DrawPrompt(x, rr.Bottom()-1)
+}
+
+// DrawBoard draws the well with the board's cells and the cursor.
+func (es *EditorScene) DrawBoard(rr Area) {
+	for y := 0; y < rr.Height+1; y++ {
+		Screen.SetContent(rr.X-1, rr.Y+y, '#', nil, defStyle)
+		Screen.SetContent(rr.Right(), rr.Y+y, '#', nil, defStyle)
+	}
+	for x := 0; x < rr.Width; x++ {
+		Screen.SetContent(rr.X+x, rr.Bottom(), '#', nil, defStyle)
+	}
+
+	for y := 0; y < BOARD_HEIGHT; y++ {
+		for x := 0; x < BOARD_WIDTH; x++ {
+			cell := es.board.Grid.MustGet(x, y)
+			r := 'o'
+			if cell == 0 {
+				r = '.'
+			}
+			style := editorCellStyle(cell)
+			if x == es.cursorX && y == es.cursorY {
+				style = style.Reverse(true)
+				if cell == 0 {
+					r = '+'
+				}
+			}
+			Screen.SetContent(rr.X+x, rr.Y+y, r, nil, style)
+		}
+	}
+}
+
+func (es *EditorScene) DrawPrompt(x, y int) {
+	promptStyle := defStyle.Bold(true)
+	switch es.prompt {
+	case NoEditorPrompt:
+		if es.status != "" {
+			SetString(x, y, es.status, defStyle)
+		}
+	case QueuePrompt, SaveBoardPrompt, FumenPrompt:
+		SetString(x, y-1, "enter:apply ctrl+q:cancel", defStyle.Dim(true))
+		SetString(x, y, es.promptField.Name, promptStyle)
+		es.promptField.Field.Draw(
+			x+1+runewidth.StringWidth(es.promptField.Name),
+			y,
+			true,
+		)
+	case LoadBoardPrompt:
+		// Show the names around the focused one, above the prompt line
+		const rows = 6
+		start := max(0, min(len(es.boardNames)-rows, es.loadFocus-rows/2))
+		for i := start; i < min(len(es.boardNames), start+rows); i++ {
+			style := defStyle
+			if i == es.loadFocus {
+				style = style.Reverse(true)
+				Screen.SetContent(x, y-rows+i-start, '*', nil, defStyle)
+			}
+			SetString(x+2, y-rows+i-start, es.boardNames[i], style)
+		}
+		SetString(x, y, "Load which board? enter:load ctrl+q:cancel", promptStyle)
+	}
+}
+
+func (es *EditorScene) Cleanup() {
+}
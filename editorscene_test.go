@@ -0,0 +1,21 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEditorCycleHold(t *testing.T) {
+	es := &EditorScene{board: NewBoard()}
+
+	var held []int
+	for i := 0; i <= len(Pieces)+1; i++ {
+		es.CycleHold()
+		held = append(held, es.board.Hold)
+	}
+
+	want := []int{0, 1, 2, 3, 4, 5, 6, NO_HOLD_PIECE, 0}
+	if !slices.Equal(held, want) {
+		t.Errorf("Hold cycled through %v, want %v", held, want)
+	}
+}
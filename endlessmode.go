@@ -13,6 +13,7 @@ func (els *EndlessSettings) Init(es *TetrisField) Objective {
 			CreateElapsedTimeStat(es),
 			CreateLinesStat(es),
 			CreatePiecesStat(es),
+			CreateFinesseStat(es),
 		},
 	}
 }
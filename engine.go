@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"math/rand"
+	"slices"
 
 	"github.com/gdamore/tcell/v2"
 )
@@ -62,6 +65,13 @@ func IsDigitRune(ev *tcell.EventKey) bool {
 type LineClearHandler func(garbage, nonGarbage int)
 type GameOverHandler func(failed bool, reason string)
 
+// SpawnHandler is called when a new piece comes out of the queue.
+type SpawnHandler func()
+
+// LockHandler is called when the piece in play is about to lock, before it is
+// added to the grid.
+type LockHandler func()
+
 type TetrisField struct {
 	audio    AudioService
 	settings GlobalTetrisSettings
@@ -113,16 +123,41 @@ type TetrisField struct {
 
 	lineClearHandlers []LineClearHandler
 	gameOverHandlers  []GameOverHandler
+	spawnHandlers     []SpawnHandler
+	lockHandlers      []LockHandler
 
 	gameOver       bool
 	failed         bool
 	gameOverReason string
 
-	finesse int64
+	// Finesse faults so far, and inputs used on the current piece
+	finesse     int64
+	pieceInputs int
+	// Frames left to flash the last piece placed with a fault, and where it
+	// was placed
+	finesseFlash int
+	finesseGrid  Grid[bool]
+	finesseX     int
+	finesseY     int
+
+	stats GameStats
+	// Whether the last thing to move the current piece was a rotation, and
+	// whether the last piece locked was spun into place
+	lastMoveRotation bool
+	lastLockSpin     bool
+	// Index of the kick used by the last rotation, and the kind of T-spin the
+	// last piece locked with
+	lastKick      int
+	lastLockTSpin SpinKind
 
 	gameStarted bool
 
 	maxStackHeight int
+
+	// How many of the next pieces are shown, and whether the hard drop
+	// indicator is hidden. Objectives with stricter rules can change these.
+	previews  int
+	hideGhost bool
 }
 
 func NewTetrisField(
@@ -165,6 +200,9 @@ func (es *TetrisField) StartGame(seed int64) {
 	es.dashParticles = InitParticles(0.1)
 	es.frameCount = 0
 	es.pieceCount = 0
+	es.stats = GameStats{}
+	es.finesse = 0
+	es.finesseFlash = 0
 
 	es.gameOver = false
 	es.failed = false
@@ -175,8 +213,13 @@ func (es *TetrisField) StartGame(seed int64) {
 
 	es.maxStackHeight = 0
 
+	es.previews = NUM_NEXT_PIECES
+	es.hideGhost = false
+
 	es.lineClearHandlers = make([]LineClearHandler, 0)
 	es.gameOverHandlers = make([]GameOverHandler, 0)
+	es.spawnHandlers = make([]SpawnHandler, 0)
+	es.lockHandlers = make([]LockHandler, 0)
 
 	es.FillNextPieces()
 
@@ -185,6 +228,19 @@ func (es *TetrisField) StartGame(seed int64) {
 
 func (es *TetrisField) HandleAction(act Action) {
 	if !es.gameOver {
+		// Count the inputs that move the piece, for finesse. A dash takes
+		// two, since ToggleSuper had to be pressed first.
+		switch act {
+		case MoveUp, RotateCW, RotateCCW:
+			es.pieceInputs++
+		case MoveLeft, MoveRight:
+			if es.shiftMode {
+				es.pieceInputs += 2
+			} else {
+				es.pieceInputs++
+			}
+		}
+
 		switch act {
 		case MoveUp:
 			es.Rotate(1)
@@ -214,6 +270,7 @@ func (es *TetrisField) Update() {
 	}
 
 	es.dashParticles.Update()
+	es.finesseFlash = max(0, es.finesseFlash-1)
 
 	if es.airborne {
 		es.gravityTimer -= es.fallRate
@@ -263,7 +320,7 @@ func (es *TetrisField) Draw(sw, sh int, rr Area, lag float64) {
 
 	es.DrawWell(gameArea)
 
-	if !es.gameOver && es.gameStarted {
+	if Display.Particles && !es.gameOver && es.gameStarted {
 		es.dashParticles.Draw(Area{
 			X:      gameArea.X,
 			Y:      gameArea.Y - 2,
@@ -273,7 +330,7 @@ func (es *TetrisField) Draw(sw, sh int, rr Area, lag float64) {
 	}
 
 	// Snap indicators
-	if es.shiftMode && !es.gameOver && es.gameStarted {
+	if Display.SnapIndicators && es.shiftMode && !es.gameOver && es.gameStarted {
 		es.DrawPiece(
 			es.cpGrid,
 			gameArea.X+es.leftSnapPosition,
@@ -312,7 +369,7 @@ func (es *TetrisField) Draw(sw, sh int, rr Area, lag float64) {
 	}
 
 	// Hard drop indicator
-	if !es.gameOver && es.gameStarted {
+	if !es.gameOver && es.gameStarted && !es.hideGhost {
 		es.DrawPiece(
 			es.cpGrid,
 			gameArea.X+es.cpX,
@@ -323,7 +380,8 @@ func (es *TetrisField) Draw(sw, sh int, rr Area, lag float64) {
 	}
 
 	// Next piece indicator
-	if BOARD_HEIGHT-es.maxStackHeight < 4 && es.gameStarted && !es.gameOver {
+	if BOARD_HEIGHT-es.maxStackHeight < 4 && es.gameStarted && !es.gameOver &&
+		es.nextPieces[0] != NO_NEXT_PIECE {
 		nextPiece := Pieces[es.nextPieces[0]][0]
 		gridOffsetX := nextPiece.Width/2 + 1
 		gridOffsetY := nextPiece.Height/2 + 1
@@ -357,6 +415,17 @@ func (es *TetrisField) Draw(sw, sh int, rr Area, lag float64) {
 
 	es.DrawGrid(gameArea)
 
+	// Blink the piece that was placed with a finesse fault
+	if Display.FinesseFlash && es.finesseFlash > 0 && es.finesseFlash/5%2 == 0 {
+		es.DrawPiece(
+			es.finesseGrid,
+			gameArea.X+es.finesseX,
+			gameArea.Y+es.finesseY-BOARD_HEIGHT,
+			'x',
+			defStyle.Foreground(tcell.ColorRed).Reverse(true),
+		)
+	}
+
 	es.DrawNextPieces(nextPieceArea)
 	es.DrawHoldPiece(holdPieceArea)
 	es.DrawCombo(comboArea)
@@ -424,7 +493,8 @@ func (es *TetrisField) DrawNextPieces(rr Area) {
 		rr.Y-1,
 		"NEXT",
 		defStyle)
-	for i := 0; i < NUM_NEXT_PIECES; i++ {
+	// Only the pieces left are shown once the generator runs out
+	for i := 0; i < es.previews && es.nextPieces[i] != NO_NEXT_PIECE; i++ {
 		piece := Pieces[es.nextPieces[i]][0]
 		gridOffsetX := piece.Width/2 + 1
 		gridOffsetY := piece.Height/2 + 1
@@ -521,6 +591,34 @@ func (es *TetrisField) DrawGrid(rr Area) {
 	}
 }
 
+// DrawGhost draws a dimmed version of the well and its pieces, used to show a
+// replay racing against the player.
+func (es *TetrisField) DrawGhost(rr Area) {
+	es.DrawWell(rr)
+
+	if es.gameStarted && !es.gameOver {
+		es.DrawPiece(
+			es.cpGrid,
+			rr.X+es.cpX,
+			rr.Y+es.cpY-BOARD_HEIGHT,
+			'o',
+			GAME_OVER_PIECE_STYLE,
+		)
+	}
+
+	for yy := BOARD_HEIGHT; yy < es.grid.Height; yy++ {
+		for xx := 0; xx < es.grid.Width; xx++ {
+			if es.grid.MustGet(xx, yy) != 0 {
+				Screen.SetContent(
+					rr.X+xx,
+					rr.Y+yy-BOARD_HEIGHT,
+					'o',
+					nil, GAME_OVER_PIECE_STYLE)
+			}
+		}
+	}
+}
+
 func (es *TetrisField) DrawGameOver(rr Area) {
 	subArea := rr.Inset(rr.Width, 4)
 	for xx := rr.Left(); xx < rr.Right(); xx++ {
@@ -578,11 +676,26 @@ func (es *TetrisField) SetPiece(idx int) {
 	es.SetAirborne()
 	es.floorKicked = false
 	es.shiftMode = false
+	es.lastMoveRotation = false
+	es.pieceInputs = 0
 }
 
 func (es *TetrisField) GetRandomPiece() {
+	idx := es.nextPieces[0]
+	// Once the generator runs out, the held piece comes out last, and after
+	// that the game is lost unless the objective already ended it
+	fromHold := idx == NO_NEXT_PIECE && es.holdPiece != NO_HOLD_PIECE
+	if fromHold {
+		idx = es.holdPiece
+	} else if idx == NO_NEXT_PIECE {
+		if !es.gameOver {
+			es.ObjectiveFailed("Out of pieces")
+		}
+		return
+	}
+
 	// If the next piece will collide with the grid, the game is over
-	nextPiece := Pieces[es.nextPieces[0]][0]
+	nextPiece := Pieces[idx][0]
 	gridOffsetX := nextPiece.Width/2 + 1
 	gridOffsetY := nextPiece.Height/2 + 1
 	if es.CheckCollision(
@@ -593,14 +706,21 @@ func (es *TetrisField) GetRandomPiece() {
 		es.BlockOut()
 		return
 	}
-	idx := es.nextPieces[0]
 
 	es.SetPiece(idx)
 
-	for i := 0; i < NUM_NEXT_PIECES-1; i++ {
-		es.nextPieces[i] = es.nextPieces[i+1]
+	if fromHold {
+		es.holdPiece = NO_HOLD_PIECE
+	} else {
+		for i := 0; i < NUM_NEXT_PIECES-1; i++ {
+			es.nextPieces[i] = es.nextPieces[i+1]
+		}
+		es.nextPieces[NUM_NEXT_PIECES-1] = es.pieceGenerator.NextPiece()
+	}
+
+	for _, handle := range es.spawnHandlers {
+		handle()
 	}
-	es.nextPieces[NUM_NEXT_PIECES-1] = es.pieceGenerator.NextPiece()
 }
 
 func (es *TetrisField) ToggleShiftMode() {
@@ -619,7 +739,7 @@ func (es *TetrisField) Rotate(offset int) {
 
 	offsets := GetOffsets(es.cpIdx, es.cpRot, newRotation)
 
-	for _, os := range offsets {
+	for i, os := range offsets {
 		if es.CheckCollision(
 			Pieces[es.cpIdx][newRotation],
 			es.cpX+os.X,
@@ -631,6 +751,8 @@ func (es *TetrisField) Rotate(offset int) {
 		es.cpRot = newRotation
 		es.cpX += os.X
 		es.cpY += os.Y
+		es.lastMoveRotation = true
+		es.lastKick = i
 
 		es.cpGrid = Pieces[es.cpIdx][es.cpRot]
 		es.SetHardDropHeight()
@@ -695,6 +817,7 @@ func (es *TetrisField) MovePiece(dx int) {
 			es.cpX = es.rightSnapPosition
 		}
 
+		es.lastMoveRotation = false
 		es.shiftMode = false
 		es.SetHardDropHeight()
 		es.SetAirborne()
@@ -711,6 +834,7 @@ func (es *TetrisField) MovePiece(dx int) {
 	}
 
 	es.cpX += dx
+	es.lastMoveRotation = false
 
 	es.SetHardDropHeight()
 	oldAirborne := es.airborne
@@ -732,6 +856,9 @@ func (es *TetrisField) SoftDrop() {
 			es.cpX, es.cpY,
 			es.cpX, es.hardDropHeight)
 		es.score += int64(es.hardDropHeight) - int64(es.cpY)
+		if es.cpY != es.hardDropHeight {
+			es.lastMoveRotation = false
+		}
 		es.cpY = es.hardDropHeight
 		es.shiftMode = false
 		es.gravityTimer = BASE_GRAVITY_UNIT
@@ -747,6 +874,7 @@ func (es *TetrisField) SoftDrop() {
 	}
 
 	es.cpY += 1
+	es.lastMoveRotation = false
 	es.score += 1
 	es.gravityTimer = BASE_GRAVITY_UNIT
 	es.SetAirborne()
@@ -763,6 +891,7 @@ func (es *TetrisField) GravityDrop() {
 	}
 
 	es.cpY += 1
+	es.lastMoveRotation = false
 
 	if es.shiftMode {
 		es.SetSnapPositions()
@@ -779,6 +908,10 @@ func (es *TetrisField) HardDrop() {
 		es.cpX, es.hardDropHeight,
 	)
 	es.score += 2 * (int64(es.hardDropHeight) - int64(es.cpY))
+	// A piece that drops after turning was moved last, not rotated
+	if es.cpY != es.hardDropHeight {
+		es.lastMoveRotation = false
+	}
 	es.cpY = es.hardDropHeight
 	es.LockPiece()
 }
@@ -787,6 +920,10 @@ func (es *TetrisField) SwapHoldPiece() {
 	if es.usedHoldPiece {
 		return
 	}
+	// Nothing would come out to replace the held piece
+	if es.holdPiece == NO_HOLD_PIECE && es.nextPieces[0] == NO_NEXT_PIECE {
+		return
+	}
 	tmp := es.holdPiece
 	es.holdPiece = es.cpIdx
 	if tmp == 8 {
@@ -796,6 +933,7 @@ func (es *TetrisField) SwapHoldPiece() {
 	}
 
 	es.usedHoldPiece = true
+	es.stats.Holds++
 }
 
 func (es *TetrisField) CheckCollision(piece Grid[bool], px, py int) bool {
@@ -815,7 +953,78 @@ func (es *TetrisField) CheckCollision(piece Grid[bool], px, py int) bool {
 	return false
 }
 
+// IsSpin reports whether the current piece was rotated into a spot it can't
+// move out of.
+func (es *TetrisField) IsSpin() bool {
+	return es.lastMoveRotation &&
+		es.CheckCollision(es.cpGrid, es.cpX-1, es.cpY) &&
+		es.CheckCollision(es.cpGrid, es.cpX+1, es.cpY) &&
+		es.CheckCollision(es.cpGrid, es.cpX, es.cpY-1)
+}
+
+// SpinKind tells T-spins apart from T-spin minis.
+type SpinKind int8
+
+const (
+	NoSpin SpinKind = iota
+	MiniSpin
+	FullSpin
+)
+
+func (sk SpinKind) String() string {
+	switch sk {
+	case NoSpin:
+		return "no spin"
+	case MiniSpin:
+		return "T-spin mini"
+	case FullSpin:
+		return "T-spin"
+	}
+	return fmt.Sprintf("SpinKind(%d)", int8(sk))
+}
+
+// Corners of the T piece's grid, clockwise from the top left. The two
+// corners on the side the T points towards start at its rotation.
+var tSpinCorners = []Position{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 2}, {X: 0, Y: 2}}
+
+// TSpinKind classifies the current piece's position by the three-corner rule.
+// A T rotated into place with three of the corners around its centre filled
+// is a T-spin if both corners it points towards are filled, or if it got
+// there with the last kick, and a mini otherwise.
+func (es *TetrisField) TSpinKind() SpinKind {
+	if es.cpIdx != T_PIECE || !es.lastMoveRotation {
+		return NoSpin
+	}
+
+	var filled [4]bool
+	count := 0
+	for i, c := range tSpinCorners {
+		cell, ok := es.grid.Get(es.cpX+c.X, es.cpY+c.Y)
+		filled[i] = !ok || cell != 0
+		if filled[i] {
+			count++
+		}
+	}
+	if count < 3 {
+		return NoSpin
+	}
+
+	if filled[es.cpRot] && filled[(es.cpRot+1)%4] ||
+		es.lastKick == len(JLSTZOffsets)-1 {
+		return FullSpin
+	}
+	return MiniSpin
+}
+
 func (es *TetrisField) LockPiece() {
+	es.lastLockSpin = es.IsSpin()
+	es.lastLockTSpin = es.TSpinKind()
+	es.CheckFinesse()
+
+	for _, handle := range es.lockHandlers {
+		handle()
+	}
+
 	for yy := 0; yy < es.cpGrid.Height; yy++ {
 		for xx := 0; xx < es.cpGrid.Width; xx++ {
 			if es.cpGrid.MustGet(xx, yy) {
@@ -1040,21 +1249,30 @@ func (es *TetrisField) ClearLines() bool {
 		es.combo = 0
 	case 1:
 		es.combo += 1
+		es.stats.Singles++
 		lineScore = SINGLE_SCORE * es.level
 	case 2:
 		es.combo += 1
+		es.stats.Doubles++
 		lineScore = DOUBLE_SCORE * es.level
 	case 3:
 		es.combo += 1
+		es.stats.Triples++
 		lineScore = TRIPLE_SCORE * es.level
 	default:
 		es.combo += 1
+		es.stats.Tetrises++
 		lineScore = TETRIS_SCORE * es.level
 	}
 
+	if len(lines) > 0 && es.lastLockSpin {
+		es.stats.Spins++
+	}
+	es.stats.MaxCombo = max(es.stats.MaxCombo, es.combo)
+
 	es.score += lineScore
 	var comboCount int
-	if comboCount >= len(COMBO_COUNTS) {
+	if es.combo >= len(COMBO_COUNTS) {
 		comboCount = 5
 	} else {
 		comboCount = COMBO_COUNTS[es.combo]
@@ -1158,6 +1376,14 @@ func (es *TetrisField) AddLineClearHandler(handler LineClearHandler) {
 	es.lineClearHandlers = append(es.lineClearHandlers, handler)
 }
 
+func (es *TetrisField) AddSpawnHandler(handler SpawnHandler) {
+	es.spawnHandlers = append(es.spawnHandlers, handler)
+}
+
+func (es *TetrisField) AddLockHandler(handler LockHandler) {
+	es.lockHandlers = append(es.lockHandlers, handler)
+}
+
 func (es *TetrisField) AddGameOverHandler(handler GameOverHandler) {
 	es.gameOverHandlers = append(es.gameOverHandlers, handler)
 }
@@ -1182,6 +1408,64 @@ func (es *TetrisField) BlockOut() {
 	}
 }
 
+// StateHash computes a hash of everything that determines how the game plays
+// out from here: the board, the current, next and held pieces, and the score.
+// Replays store these periodically to detect when playback diverges.
+func (es *TetrisField) StateHash() uint64 {
+	h := fnv.New64a()
+
+	cells := make([]byte, len(es.grid.data))
+	for i, c := range es.grid.data {
+		cells[i] = byte(c)
+	}
+	h.Write(cells)
+
+	state := []int64{
+		int64(es.cpIdx),
+		int64(es.cpX),
+		int64(es.cpY),
+		int64(es.cpRot),
+		int64(es.holdPiece),
+		es.score,
+		es.lines,
+	}
+	for _, p := range es.nextPieces {
+		state = append(state, int64(p))
+	}
+	binary.Write(h, binary.LittleEndian, state)
+
+	return h.Sum64()
+}
+
+// BoardIsEmpty reports whether every cell of the board is clear, as it is
+// after a perfect clear.
+func (es *TetrisField) BoardIsEmpty() bool {
+	return !slices.ContainsFunc(es.grid.data, func(cell int) bool {
+		return cell != 0
+	})
+}
+
+// ClearBoard empties the board and the hold, leaving the piece in play and
+// the queue as they are.
+func (es *TetrisField) ClearBoard() {
+	es.grid = MakeGrid(BOARD_WIDTH, BOARD_HEIGHT*2, 0)
+	es.holdPiece = NO_HOLD_PIECE
+	es.maxStackHeight = 0
+	es.SetHardDropHeight()
+}
+
+// ObjectiveFailed ends the game as a loss, for objectives that can be lost
+// without topping out.
+func (es *TetrisField) ObjectiveFailed(text string) {
+	es.gameOver = true
+	es.failed = true
+	es.gameOverReason = text
+
+	for _, handle := range es.gameOverHandlers {
+		handle(es.failed, es.gameOverReason)
+	}
+}
+
 func (es *TetrisField) ObjectiveComplete(text string) {
 	es.gameOver = true
 	es.failed = false
@@ -0,0 +1,155 @@
+package main
+
+import "strings"
+
+// Number of frames a piece placed with a finesse fault flashes for
+const FINESSE_FLASH_FRAMES = 30
+
+// FinessePlacement identifies where a piece ends up, regardless of which
+// rotation state got it there: S, Z and I pieces look the same in opposite
+// rotations, and O pieces in all of them.
+type FinessePlacement struct {
+	Shape string
+	X     int
+}
+
+func NewFinessePlacement(piece Grid[bool], px int) FinessePlacement {
+	minX, maxX := piece.Width, -1
+	minY, maxY := piece.Height, -1
+	for yy := 0; yy < piece.Height; yy++ {
+		for xx := 0; xx < piece.Width; xx++ {
+			if piece.MustGet(xx, yy) {
+				minX, maxX = min(minX, xx), max(maxX, xx)
+				minY, maxY = min(minY, yy), max(maxY, yy)
+			}
+		}
+	}
+
+	var shape strings.Builder
+	for yy := minY; yy <= maxY; yy++ {
+		for xx := minX; xx <= maxX; xx++ {
+			if piece.MustGet(xx, yy) {
+				shape.WriteByte('#')
+			} else {
+				shape.WriteByte('.')
+			}
+		}
+		shape.WriteByte('/')
+	}
+
+	return FinessePlacement{Shape: shape.String(), X: px + minX}
+}
+
+// Minimum number of inputs needed to reach every placement of each piece,
+// indexed like Pieces
+var FINESSE_TABLE = buildFinesseTable()
+
+type finesseState struct {
+	x, y, rot int
+}
+
+// Works out the fewest inputs that move each piece from where it spawns to
+// every column and rotation on an empty board. Moves and rotations take one
+// input each, and dashing to a wall takes two: ToggleSuper and the move.
+func buildFinesseTable() []map[FinessePlacement]int {
+	field := &TetrisField{grid: MakeGrid(BOARD_WIDTH, BOARD_HEIGHT*2, 0)}
+
+	table := make([]map[FinessePlacement]int, len(Pieces))
+	for idx := range Pieces {
+		start := finesseState{
+			x:   BOARD_WIDTH/2 - (Pieces[idx][0].Width/2 + 1),
+			y:   BOARD_HEIGHT - (Pieces[idx][0].Height/2 + 1),
+			rot: 0,
+		}
+
+		collides := func(s finesseState) bool {
+			return field.CheckCollision(Pieces[idx][s.rot], s.x, s.y)
+		}
+		neighbors := func(s finesseState) []finesseState {
+			var next []finesseState
+			for _, dx := range []int{-1, 1} {
+				moved := finesseState{s.x + dx, s.y, s.rot}
+				if !collides(moved) {
+					next = append(next, moved)
+				}
+			}
+			for _, offset := range []int{-1, 1} {
+				rot := (s.rot + offset + 4) % 4
+				for _, os := range GetOffsets(idx, s.rot, rot) {
+					rotated := finesseState{s.x + os.X, s.y + os.Y, rot}
+					if !collides(rotated) {
+						next = append(next, rotated)
+						break
+					}
+				}
+			}
+			return next
+		}
+		dashes := func(s finesseState) []finesseState {
+			var next []finesseState
+			for _, dx := range []int{-1, 1} {
+				dashed := s
+				for !collides(finesseState{dashed.x + dx, dashed.y, dashed.rot}) {
+					dashed.x += dx
+				}
+				next = append(next, dashed)
+			}
+			return next
+		}
+
+		// Inputs cost one or two, so keep relaxing until nothing improves.
+		// There are only a few dozen states.
+		cost := map[finesseState]int{start: 0}
+		for changed := true; changed; {
+			changed = false
+			for s, c := range cost {
+				for _, n := range neighbors(s) {
+					if old, ok := cost[n]; !ok || c+1 < old {
+						cost[n] = c + 1
+						changed = true
+					}
+				}
+				for _, n := range dashes(s) {
+					if old, ok := cost[n]; !ok || c+2 < old {
+						cost[n] = c + 2
+						changed = true
+					}
+				}
+			}
+		}
+
+		table[idx] = make(map[FinessePlacement]int)
+		for s, c := range cost {
+			placement := NewFinessePlacement(Pieces[idx][s.rot], s.x)
+			if old, ok := table[idx][placement]; !ok || c < old {
+				table[idx][placement] = c
+			}
+		}
+	}
+
+	return table
+}
+
+// CheckFinesse compares the inputs used to place the current piece against
+// the fewest that could have placed it. Pieces that were tucked or spun under
+// an overhang can't be dropped straight from where they spawned, so they
+// aren't judged.
+func (es *TetrisField) CheckFinesse() {
+	best, ok := FINESSE_TABLE[es.cpIdx][NewFinessePlacement(es.cpGrid, es.cpX)]
+	if !ok || es.pieceInputs <= best {
+		return
+	}
+
+	spawnY := BOARD_HEIGHT - (es.cpGrid.Height/2 + 1)
+	for y := spawnY; y < es.cpY; y++ {
+		if es.CheckCollision(es.cpGrid, es.cpX, y) {
+			return
+		}
+	}
+
+	es.finesse++
+	es.finesseFlash = FINESSE_FLASH_FRAMES
+	es.finesseGrid = es.cpGrid
+	es.finesseX = es.cpX
+	es.finesseY = es.cpY
+}
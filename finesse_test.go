@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+func TestFinesseTable(t *testing.T) {
+	const (
+		I = 0
+		O = 3
+		T = 5
+	)
+
+	for _, tc := range []struct {
+		piece int
+		shape string
+		// Fewest inputs to put the leftmost cell in each column
+		inputs []int
+	}{
+		// Spawns at column 4, and dashes to either wall
+		{O, "##/##/", []int{2, 3, 2, 1, 0, 1, 2, 3, 2}},
+		{I, "####/", []int{2, 2, 1, 0, 1, 2, 2}},
+		// One rotation, then the same as flat, give or take the column the
+		// vertical I ends up in
+		{I, "#/#/#/#/", []int{3, 3, 3, 2, 1, 1, 2, 3, 3, 3}},
+		{T, ".#./###/", []int{2, 2, 1, 0, 1, 2, 3, 2}},
+		{T, "###/.#./", []int{4, 4, 3, 2, 3, 4, 5, 4}},
+		{T, "#./##/#./", []int{3, 3, 3, 2, 1, 2, 3, 4, 3}},
+	} {
+		for x, want := range tc.inputs {
+			got, ok := FINESSE_TABLE[tc.piece][FinessePlacement{tc.shape, x}]
+			if !ok {
+				t.Errorf("%c %v at %d: no entry", PIECE_LETTERS[tc.piece], tc.shape, x)
+			} else if got != want {
+				t.Errorf(
+					"%c %v at %d: %d inputs, want %d",
+					PIECE_LETTERS[tc.piece],
+					tc.shape,
+					x,
+					got,
+					want,
+				)
+			}
+		}
+		if _, ok := FINESSE_TABLE[tc.piece][FinessePlacement{tc.shape, len(tc.inputs)}]; ok {
+			t.Errorf("%c %v: entry past the right wall", PIECE_LETTERS[tc.piece], tc.shape)
+		}
+	}
+}
+
+func TestCheckFinesse(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		acts   []Action
+		faults int64
+	}{
+		{"straight down", []Action{HardDrop}, 0},
+		{"one step", []Action{MoveLeft, HardDrop}, 0},
+		{"there and back", []Action{MoveLeft, MoveRight, HardDrop}, 1},
+		{"dash to the wall", []Action{ToggleSuper, MoveLeft, HardDrop}, 0},
+		{"tapping to the wall", []Action{MoveLeft, MoveLeft, MoveLeft, MoveLeft, HardDrop}, 1},
+		// Three inputs are the fewest for the column next to the wall
+		{"dash and step back", []Action{ToggleSuper, MoveLeft, MoveRight, HardDrop}, 0},
+		{"dash both ways", []Action{
+			ToggleSuper, MoveLeft, ToggleSuper, MoveRight, HardDrop,
+		}, 1},
+		{"spin in place", []Action{RotateCW, RotateCW, RotateCW, RotateCW, HardDrop}, 1},
+	} {
+		es := NewTetrisField(1, DefaultTetrisSettings)
+		es.SetPiece(3)
+		for _, act := range tc.acts {
+			es.HandleAction(act)
+		}
+		if es.finesse != tc.faults {
+			t.Errorf("%v: %d faults, want %d", tc.name, es.finesse, tc.faults)
+		}
+	}
+}
+
+func TestCheckFinesseSkipsTucks(t *testing.T) {
+	for _, overhang := range []bool{false, true} {
+		es := NewTetrisField(1, DefaultTetrisSettings)
+		// An overhang over the left wall that an O can only reach by
+		// sliding under it
+		if overhang {
+			for x := 0; x < 3; x++ {
+				es.grid.Set(x, BOARD_HEIGHT*2-3, 1)
+			}
+		}
+		es.SetPiece(3)
+		es.cpX, es.cpY = -1, BOARD_HEIGHT*2-2
+		es.pieceInputs = 10
+		es.LockPiece()
+
+		want := int64(1)
+		if overhang {
+			want = 0
+		}
+		if es.finesse != want {
+			t.Errorf("overhang %v: %d faults, want %d", overhang, es.finesse, want)
+		}
+	}
+}
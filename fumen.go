@@ -0,0 +1,730 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Fumen is the format boards are shared in online, as used by
+// fumen.zui.jp and the tetris-fumen library. Only version 115 is supported.
+const FUMEN_PREFIX = "v115@"
+
+// Other prefixes of version 115 strings, which open the same data in a
+// different view
+var FUMEN_VIEW_PREFIXES = []string{"v115@", "m115@", "d115@", "D115@"}
+
+const FUMEN_DIGITS = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// Fumen fields have 23 rows, plus a row of garbage waiting below the floor
+const (
+	FUMEN_WIDTH  = 10
+	FUMEN_HEIGHT = 23
+	FUMEN_BLOCKS = FUMEN_WIDTH * (FUMEN_HEIGHT + 1)
+)
+
+// Characters that comments can hold, after being escaped. A comment stores
+// four characters in five digits, as a number in base 96.
+const (
+	FUMEN_COMMENT_CHARS = " !\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+	FUMEN_COMMENT_BASE  = 96
+	// Comments are limited by the two digits that store their length
+	FUMEN_COMMENT_MAX_LENGTH = 64*64 - 1
+)
+
+// Where exported Fumen strings are written
+const FUMEN_DIR = "fumen"
+
+const FUMEN_FILE_EXTENSION = ".txt"
+
+// Fumen numbers pieces as I, L, O, Z, T, J, S after an empty cell, and uses 8
+// for garbage. These convert to and from grid cells.
+var (
+	FUMEN_TO_CELL = []int{0, 1, 3, 4, 7, 6, 2, 5, GARBAGE_CELL}
+	CELL_TO_FUMEN = []int{0, 1, 6, 2, 3, 7, 5, 4, 8}
+)
+
+// Fumen numbers rotations as reverse, right, spawn, left. The mapping happens
+// to be its own inverse.
+var FUMEN_ROTATIONS = []int{2, 1, 0, 3}
+
+// Cells of each piece in its spawn rotation, relative to the cell it rotates
+// around, with y pointing up. Indexed like Pieces.
+var FUMEN_PIECE_BLOCKS = [][][2]int{
+	{{0, 0}, {-1, 0}, {1, 0}, {2, 0}},
+	{{0, 0}, {-1, 0}, {1, 0}, {-1, 1}},
+	{{0, 0}, {-1, 0}, {1, 0}, {1, 1}},
+	{{0, 0}, {1, 0}, {0, 1}, {1, 1}},
+	{{0, 0}, {-1, 0}, {0, 1}, {1, 1}},
+	{{0, 0}, {-1, 0}, {1, 0}, {0, 1}},
+	{{0, 0}, {1, 0}, {0, 1}, {-1, 1}},
+}
+
+// Quiz comments give the hold piece, the current piece and the queue, such as
+// "#Q=[T](S)ZLJ"
+var fumenQuizPattern = regexp.MustCompile(
+	`^#Q=\[([IJLOSTZ]?)\]\(([IJLOSTZ]?)\)([IJLOSTZ]*)`,
+)
+
+var ErrInvalidFumen = errors.New("Invalid fumen")
+
+// FumenPiece is a piece shown on a page. X and Y are the cell it rotates
+// around, counted from the left and from the bottom row. Piece and Rotation
+// are numbered as in Pieces.
+type FumenPiece struct {
+	Piece    int
+	Rotation int
+	X, Y     int
+}
+
+// Blocks returns the cells the piece covers, with y counted from the bottom.
+func (fp FumenPiece) Blocks() [][2]int {
+	blocks := make([][2]int, 0, 4)
+	for _, b := range FUMEN_PIECE_BLOCKS[fp.Piece] {
+		x, y := b[0], b[1]
+		for i := 0; i < fp.Rotation; i++ {
+			x, y = y, -x
+		}
+		blocks = append(blocks, [2]int{fp.X + x, fp.Y + y})
+	}
+	return blocks
+}
+
+// Fumen stores a few pieces around a different cell than the one they
+// rotate around. Returns how far the stored cell is from the real one.
+func (fp FumenPiece) storedOffset() (int, int) {
+	switch {
+	case fp.Piece == 3 && fp.Rotation == 3:
+		return -1, 1
+	case fp.Piece == 3 && fp.Rotation == 2:
+		return -1, 0
+	case fp.Piece == 3 && fp.Rotation == 0:
+		return 0, 1
+	case fp.Piece == 0 && fp.Rotation == 2:
+		return -1, 0
+	case fp.Piece == 0 && fp.Rotation == 3:
+		return 0, 1
+	case fp.Piece == 4 && fp.Rotation == 0:
+		return 0, 1
+	case fp.Piece == 4 && fp.Rotation == 1:
+		return 1, 0
+	case fp.Piece == 6 && fp.Rotation == 0:
+		return 0, 1
+	case fp.Piece == 6 && fp.Rotation == 3:
+		return -1, 0
+	}
+	return 0, 0
+}
+
+// FumenPage is one page of a Fumen. The field has FUMEN_HEIGHT rows, top to
+// bottom, followed by the row of garbage below the floor, with cells
+// numbered as in the game's grid.
+type FumenPage struct {
+	Field Grid[int]
+	// Piece shown on the page, if any
+	Piece   *FumenPiece
+	Comment string
+
+	// Whether the piece is locked into the field for the next page, and what
+	// happens to the field afterwards
+	Lock   bool
+	Rise   bool
+	Mirror bool
+}
+
+func NewFumenField() Grid[int] {
+	return MakeGrid(FUMEN_WIDTH, FUMEN_HEIGHT+1, 0)
+}
+
+// NextField returns the field the page leaves for the next one: the piece
+// is locked and lines are cleared, then the garbage row rises and the field is
+// mirrored if the page asks for it.
+func (fp *FumenPage) NextField() Grid[int] {
+	field := fp.Field.Clone()
+	if !fp.Lock {
+		return field
+	}
+
+	if fp.Piece != nil {
+		for _, b := range fp.Piece.Blocks() {
+			field.Set(b[0], FUMEN_HEIGHT-1-b[1], fp.Piece.Piece+1)
+		}
+	}
+
+	// Clear full rows, leaving the garbage row alone
+	rows := make([][]int, 0, FUMEN_HEIGHT)
+	for y := 0; y < FUMEN_HEIGHT; y++ {
+		row := make([]int, FUMEN_WIDTH)
+		for x := range row {
+			row[x] = field.MustGet(x, y)
+		}
+		if !slices.Contains(row, 0) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	for len(rows) < FUMEN_HEIGHT {
+		rows = slices.Insert(rows, 0, make([]int, FUMEN_WIDTH))
+	}
+
+	if fp.Rise {
+		garbage := make([]int, FUMEN_WIDTH)
+		for x := range garbage {
+			garbage[x] = field.MustGet(x, FUMEN_HEIGHT)
+			field.Set(x, FUMEN_HEIGHT, 0)
+		}
+		rows = append(rows[1:], garbage)
+	}
+	if fp.Mirror {
+		for _, row := range rows {
+			slices.Reverse(row)
+		}
+	}
+
+	for y, row := range rows {
+		for x, cell := range row {
+			field.Set(x, y, cell)
+		}
+	}
+	return field
+}
+
+// Reads numbers written as little-endian base 64 digits.
+type fumenReader struct {
+	digits []int
+}
+
+func (fr *fumenReader) poll(n int) (int, error) {
+	if len(fr.digits) < n {
+		return 0, fmt.Errorf("%w: data ends early", ErrInvalidFumen)
+	}
+	value := 0
+	for i := n - 1; i >= 0; i-- {
+		value = value*64 + fr.digits[i]
+	}
+	fr.digits = fr.digits[n:]
+	return value, nil
+}
+
+// DecodeFumen reads every page of a Fumen string. Links to Fumen viewers are
+// accepted too.
+func DecodeFumen(text string) ([]FumenPage, error) {
+	text = strings.TrimSpace(text)
+	start := -1
+	for _, prefix := range FUMEN_VIEW_PREFIXES {
+		if i := strings.Index(text, prefix); i >= 0 {
+			start = i + len(prefix)
+			break
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("%w: expected a version 115 fumen", ErrInvalidFumen)
+	}
+	// Links may carry other parameters after the data
+	data, _, _ := strings.Cut(text[start:], "&")
+
+	fr := &fumenReader{}
+	for _, r := range data {
+		if r == '?' {
+			continue
+		}
+		digit := strings.IndexRune(FUMEN_DIGITS, r)
+		if digit < 0 {
+			return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidFumen, r)
+		}
+		fr.digits = append(fr.digits, digit)
+	}
+
+	var pages []FumenPage
+	prev := NewFumenField()
+	comment := ""
+	repeat := 0
+	for len(fr.digits) > 0 {
+		page := FumenPage{Field: prev.Clone()}
+
+		if repeat > 0 {
+			repeat--
+		} else {
+			changed, err := decodeFumenField(fr, page.Field)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				repeat, err = fr.poll(1)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		value, err := fr.poll(3)
+		if err != nil {
+			return nil, err
+		}
+		hasComment := decodeFumenAction(value, &page)
+
+		if hasComment {
+			comment, err = decodeFumenComment(fr)
+			if err != nil {
+				return nil, err
+			}
+		}
+		page.Comment = comment
+
+		pages = append(pages, page)
+		prev = page.NextField()
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: no pages", ErrInvalidFumen)
+	}
+	return pages, nil
+}
+
+// Applies the changes to a field stored as runs of cells that differ from the
+// previous page by the same amount. Reports whether anything changed.
+func decodeFumenField(fr *fumenReader, field Grid[int]) (bool, error) {
+	changed := true
+	idx := 0
+	for idx < FUMEN_BLOCKS {
+		value, err := fr.poll(2)
+		if err != nil {
+			return false, err
+		}
+		diff := value/FUMEN_BLOCKS - 8
+		count := value%FUMEN_BLOCKS + 1
+		if diff == 0 && count == FUMEN_BLOCKS {
+			changed = false
+		}
+		if idx+count > FUMEN_BLOCKS {
+			return false, fmt.Errorf("%w: field is too large", ErrInvalidFumen)
+		}
+
+		for ; count > 0; count-- {
+			x, y := idx%FUMEN_WIDTH, idx/FUMEN_WIDTH
+			fumenCell := CELL_TO_FUMEN[field.MustGet(x, y)] + diff
+			if fumenCell < 0 || fumenCell >= len(FUMEN_TO_CELL) {
+				return false, fmt.Errorf("%w: invalid cell", ErrInvalidFumen)
+			}
+			field.Set(x, y, FUMEN_TO_CELL[fumenCell])
+			idx++
+		}
+	}
+	return changed, nil
+}
+
+// Unpacks a page's piece and flags. Reports whether a new comment follows.
+func decodeFumenAction(value int, page *FumenPage) bool {
+	next := func(base int) int {
+		n := value % base
+		value /= base
+		return n
+	}
+
+	piece := FUMEN_TO_CELL[next(8)] - 1
+	rotation := FUMEN_ROTATIONS[next(4)]
+	location := next(FUMEN_BLOCKS)
+	page.Rise = next(2) == 1
+	page.Mirror = next(2) == 1
+	// Whether the guideline colors are used, which is always the case here
+	next(2)
+	hasComment := next(2) == 1
+	page.Lock = next(2) == 0
+
+	if piece >= 0 && piece < len(Pieces) {
+		fp := FumenPiece{
+			Piece:    piece,
+			Rotation: rotation,
+			X:        location % FUMEN_WIDTH,
+			Y:        FUMEN_HEIGHT - 1 - location/FUMEN_WIDTH,
+		}
+		dx, dy := fp.storedOffset()
+		fp.X -= dx
+		fp.Y -= dy
+		page.Piece = &fp
+	}
+
+	return hasComment
+}
+
+func decodeFumenComment(fr *fumenReader) (string, error) {
+	length, err := fr.poll(2)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for i := 0; i < (length+3)/4; i++ {
+		value, err := fr.poll(5)
+		if err != nil {
+			return "", err
+		}
+		for j := 0; j < 4; j++ {
+			c := value % FUMEN_COMMENT_BASE
+			value /= FUMEN_COMMENT_BASE
+			if c < len(FUMEN_COMMENT_CHARS) {
+				sb.WriteByte(FUMEN_COMMENT_CHARS[c])
+			}
+		}
+	}
+
+	escaped := sb.String()
+	return unescapeFumenComment(escaped[:min(length, len(escaped))]), nil
+}
+
+// Writes numbers as little-endian base 64 digits.
+type fumenWriter struct {
+	digits []int
+}
+
+func (fw *fumenWriter) push(value, n int) {
+	for i := 0; i < n; i++ {
+		fw.digits = append(fw.digits, value%64)
+		value /= 64
+	}
+}
+
+// EncodeFumen writes pages as a Fumen string.
+func EncodeFumen(pages []FumenPage) string {
+	fw := &fumenWriter{}
+	prev := NewFumenField()
+	comment := ""
+	// Where the count of following pages that reuse an unchanged field is
+	// stored, or -1 if the last page changed the field
+	repeatIdx := -1
+
+	for _, page := range pages {
+		runs := encodeFumenField(prev, page.Field)
+		switch {
+		case len(runs) > 1 || runs[0] != 8*FUMEN_BLOCKS+FUMEN_BLOCKS-1:
+			for _, run := range runs {
+				fw.push(run, 2)
+			}
+			repeatIdx = -1
+		case repeatIdx < 0 || fw.digits[repeatIdx] == 63:
+			fw.push(runs[0], 2)
+			fw.push(0, 1)
+			repeatIdx = len(fw.digits) - 1
+		default:
+			fw.digits[repeatIdx]++
+		}
+
+		escaped := escapeFumenComment(page.Comment)
+		if len(escaped) > FUMEN_COMMENT_MAX_LENGTH {
+			escaped = escaped[:FUMEN_COMMENT_MAX_LENGTH]
+		}
+		hasComment := page.Comment != comment
+		comment = page.Comment
+
+		fw.push(encodeFumenAction(page, hasComment), 3)
+
+		if hasComment {
+			fw.push(len(escaped), 2)
+			for j := 0; j < len(escaped); j += 4 {
+				value := 0
+				chunk := escaped[j:min(j+4, len(escaped))]
+				for k := len(chunk) - 1; k >= 0; k-- {
+					c := strings.IndexByte(FUMEN_COMMENT_CHARS, chunk[k])
+					value = value*FUMEN_COMMENT_BASE + c
+				}
+				fw.push(value, 5)
+			}
+		}
+
+		prev = page.NextField()
+	}
+
+	var sb strings.Builder
+	sb.WriteString(FUMEN_PREFIX)
+	// Viewers expect a '?' after every 47 characters, counting the prefix
+	for i, digit := range fw.digits {
+		if i >= 42 && (i-42)%47 == 0 {
+			sb.WriteByte('?')
+		}
+		sb.WriteByte(FUMEN_DIGITS[digit])
+	}
+	return sb.String()
+}
+
+// Describes how a field differs from the previous one, as runs of cells that
+// differ by the same amount.
+func encodeFumenField(prev, field Grid[int]) []int {
+	var runs []int
+	prevDiff, count := -1, 0
+	for y := 0; y < FUMEN_HEIGHT+1; y++ {
+		for x := 0; x < FUMEN_WIDTH; x++ {
+			diff := CELL_TO_FUMEN[field.MustGet(x, y)] -
+				CELL_TO_FUMEN[prev.MustGet(x, y)] + 8
+			if diff != prevDiff && count > 0 {
+				runs = append(runs, prevDiff*FUMEN_BLOCKS+count-1)
+				count = 0
+			}
+			prevDiff = diff
+			count++
+		}
+	}
+	return append(runs, prevDiff*FUMEN_BLOCKS+count-1)
+}
+
+func encodeFumenAction(page FumenPage, hasComment bool) int {
+	flag := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	value := flag(!page.Lock)
+	value = value*2 + flag(hasComment)
+	// Always use the guideline colors
+	value = value*2 + 1
+	value = value*2 + flag(page.Mirror)
+	value = value*2 + flag(page.Rise)
+
+	piece, rotation, location := 0, 0, 0
+	if page.Piece != nil {
+		fp := page.Piece
+		dx, dy := fp.storedOffset()
+		piece = CELL_TO_FUMEN[fp.Piece+1]
+		rotation = FUMEN_ROTATIONS[fp.Rotation]
+		location = (FUMEN_HEIGHT-1-(fp.Y+dy))*FUMEN_WIDTH + fp.X + dx
+	}
+
+	value = value*FUMEN_BLOCKS + location
+	value = value*4 + rotation
+	return value*8 + piece
+}
+
+// Comments are escaped the way JavaScript's escape function does it.
+func escapeFumenComment(comment string) string {
+	var sb strings.Builder
+	for _, r := range comment {
+		switch {
+		case r < 128 && (r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' ||
+			r >= '0' && r <= '9' || strings.ContainsRune("@*_+-./", r)):
+			sb.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&sb, "%%%02X", r)
+		default:
+			// Characters outside the basic plane are stored as two halves
+			for _, unit := range utf16Units(r) {
+				fmt.Fprintf(&sb, "%%u%04X", unit)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func utf16Units(r rune) []rune {
+	if r < 0x10000 {
+		return []rune{r}
+	}
+	r -= 0x10000
+	return []rune{0xD800 + r>>10, 0xDC00 + r&0x3FF}
+}
+
+func unescapeFumenComment(escaped string) string {
+	var units []uint16
+	var sb strings.Builder
+	flush := func() {
+		for len(units) > 0 {
+			r := rune(units[0])
+			units = units[1:]
+			if r >= 0xD800 && r < 0xDC00 && len(units) > 0 {
+				r = 0x10000 + (r-0xD800)<<10 + rune(units[0]) - 0xDC00
+				units = units[1:]
+			}
+			sb.WriteRune(r)
+		}
+	}
+
+	for i := 0; i < len(escaped); i++ {
+		if escaped[i] == '%' {
+			if i+6 <= len(escaped) && escaped[i+1] == 'u' {
+				if n, err := strconv.ParseUint(escaped[i+2:i+6], 16, 16); err == nil {
+					units = append(units, uint16(n))
+					i += 5
+					continue
+				}
+			}
+			if i+3 <= len(escaped) {
+				if n, err := strconv.ParseUint(escaped[i+1:i+3], 16, 8); err == nil {
+					units = append(units, uint16(n))
+					i += 2
+					continue
+				}
+			}
+		}
+		units = append(units, uint16(escaped[i]))
+	}
+	flush()
+	return sb.String()
+}
+
+// FumenPage describes the board as a single page. The hold piece and queue
+// are kept in a quiz comment, which is how Fumen viewers show them.
+func (b *Board) FumenPage() FumenPage {
+	field := NewFumenField()
+	offset := FUMEN_HEIGHT - b.Grid.Height
+	for y := 0; y < b.Grid.Height; y++ {
+		for x := 0; x < b.Grid.Width; x++ {
+			field.Set(x, y+offset, b.Grid.MustGet(x, y))
+		}
+	}
+
+	page := FumenPage{Field: field, Lock: true}
+	if b.Hold != NO_HOLD_PIECE || len(b.Queue) > 0 {
+		hold, current, next := "", "", ""
+		if b.Hold != NO_HOLD_PIECE {
+			hold = string(PIECE_LETTERS[b.Hold])
+		}
+		if len(b.Queue) > 0 {
+			current = string(PIECE_LETTERS[b.Queue[0]])
+			next = FormatQueue(b.Queue[1:])
+		}
+		page.Comment = fmt.Sprintf("#Q=[%v](%v)%v", hold, current, next)
+	}
+	return page
+}
+
+// BoardFromFumen makes a board from one page of a Fumen. The hold piece and
+// queue come from the page's quiz comment if it has one. Otherwise the queue
+// is made of the pieces shown on that page and the ones after it.
+func BoardFromFumen(pages []FumenPage, index int) (*Board, error) {
+	if index < 0 || index >= len(pages) {
+		return nil, fmt.Errorf("Fumen has no page %v", index+1)
+	}
+	page := pages[index]
+
+	b := NewBoard()
+	offset := FUMEN_HEIGHT - b.Grid.Height
+	for y := 0; y < FUMEN_HEIGHT; y++ {
+		for x := 0; x < FUMEN_WIDTH; x++ {
+			cell := page.Field.MustGet(x, y)
+			if y < offset {
+				if cell != 0 {
+					return nil, fmt.Errorf(
+						"Board is taller than %v rows", b.Grid.Height)
+				}
+				continue
+			}
+			b.Grid.Set(x, y-offset, cell)
+		}
+	}
+
+	if m := fumenQuizPattern.FindStringSubmatch(page.Comment); m != nil {
+		if m[1] != "" {
+			b.Hold, _ = PieceFromLetter(rune(m[1][0]))
+		}
+		b.Queue, _ = ParseQueue(m[2] + m[3])
+		return b, nil
+	}
+
+	for _, p := range pages[index:] {
+		if p.Piece != nil {
+			b.Queue = append(b.Queue, p.Piece.Piece)
+		}
+	}
+	return b, nil
+}
+
+// Returns the piece in play as Fumen places it, or nil if it's outside the
+// rows Fumen can show.
+func (es *TetrisField) fumenPiece() *FumenPiece {
+	var cells [][2]int
+	for yy := 0; yy < es.cpGrid.Height; yy++ {
+		for xx := 0; xx < es.cpGrid.Width; xx++ {
+			if es.cpGrid.MustGet(xx, yy) {
+				cells = append(cells, [2]int{
+					es.cpX + xx,
+					es.grid.Height - 1 - (es.cpY + yy),
+				})
+			}
+		}
+	}
+
+	// The piece rotates around one of its own cells
+	for _, c := range cells {
+		fp := FumenPiece{
+			Piece:    es.cpIdx,
+			Rotation: es.cpRot,
+			X:        c[0],
+			Y:        c[1],
+		}
+		blocks := fp.Blocks()
+		matches := true
+		for _, b := range blocks {
+			if !slices.Contains(cells, b) || b[1] >= FUMEN_HEIGHT {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return &fp
+		}
+	}
+	return nil
+}
+
+// Returns the visible rows of the field, and as many above them as Fumen
+// shows.
+func (es *TetrisField) fumenField() Grid[int] {
+	field := NewFumenField()
+	offset := es.grid.Height - FUMEN_HEIGHT
+	for y := 0; y < FUMEN_HEIGHT; y++ {
+		for x := 0; x < FUMEN_WIDTH; x++ {
+			field.Set(x, y, es.grid.MustGet(x, y+offset))
+		}
+	}
+	return field
+}
+
+// FumenPages plays back the replay and makes a page for every placement,
+// showing the board and where the piece went. A last page shows how the game
+// ended.
+func (rd ReplayData) FumenPages() []FumenPage {
+	rp := NewReplayPlayer(rd)
+
+	var pages []FumenPage
+	rp.Field.AddLockHandler(func() {
+		pages = append(pages, FumenPage{
+			Field: rp.Field.fumenField(),
+			Piece: rp.Field.fumenPiece(),
+			Lock:  true,
+		})
+	})
+	for !rp.Done() {
+		rp.Step()
+	}
+
+	return append(pages, FumenPage{
+		Field: rp.Field.fumenField(),
+		Lock:  true,
+	})
+}
+
+// ExportFumenFile writes a Fumen string to the export directory and returns
+// the path it was written to.
+func ExportFumenFile(name string, fumen string) (string, error) {
+	err := ValidateReplayName(name)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(FUMEN_DIR, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(FUMEN_DIR, name+FUMEN_FILE_EXTENSION)
+	err = os.WriteFile(path, []byte(fumen+"\n"), 0644)
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestFumenKnownStrings(t *testing.T) {
+	tests := []struct {
+		fumen  string
+		pieces []FumenPiece
+	}{
+		{
+			// Empty field
+			fumen:  "v115@vhAAgH",
+			pieces: []FumenPiece{},
+		},
+		{
+			// T piece lying flat on the floor
+			fumen:  "v115@vhAVQJ",
+			pieces: []FumenPiece{{Piece: 5, Rotation: 0, X: 4, Y: 0}},
+		},
+		{
+			// Six pages sharing one field, each placing a piece
+			fumen: "v115@vhFRQJUGJKJJvMJTNJGBJ",
+			pieces: []FumenPiece{
+				{Piece: 0, Rotation: 0, X: 4, Y: 0},
+				{Piece: 6, Rotation: 0, X: 4, Y: 1},
+				{Piece: 2, Rotation: 1, X: 0, Y: 1},
+				{Piece: 4, Rotation: 1, X: 6, Y: 1},
+				{Piece: 3, Rotation: 0, X: 8, Y: 0},
+				{Piece: 1, Rotation: 2, X: 4, Y: 3},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		pages, err := DecodeFumen(test.fumen)
+		if err != nil {
+			t.Fatalf("Could not decode %v: %v", test.fumen, err)
+		}
+
+		pieces := []FumenPiece{}
+		for _, page := range pages {
+			if page.Piece != nil {
+				pieces = append(pieces, *page.Piece)
+			}
+		}
+		if !reflect.DeepEqual(pieces, test.pieces) {
+			t.Errorf("Wrong pieces in %v (expected %v, got %v)",
+				test.fumen, test.pieces, pieces)
+		}
+
+		encoded := EncodeFumen(pages)
+		if encoded != test.fumen {
+			t.Errorf("Fumen differs after round trip (old: %v, new: %v)",
+				test.fumen, encoded)
+		}
+	}
+}
+
+func TestFumenBoards(t *testing.T) {
+	board := NewBoard()
+	board.AddGarbageRow(3)
+	board.AddGarbageRow(5)
+	board.Grid.Set(0, BOARD_HEIGHT-3, 1)
+	board.Grid.Set(9, 0, 7)
+	board.Hold = 5
+	board.Queue = []int{0, 1, 2, 3, 4, 5, 6}
+
+	fumen := EncodeFumen([]FumenPage{board.FumenPage()})
+	pages, err := DecodeFumen("https://fumen.zui.jp/?" + fumen)
+	if err != nil {
+		t.Fatalf("Could not decode %v: %v", fumen, err)
+	}
+
+	newBoard, err := BoardFromFumen(pages, 0)
+	if err != nil {
+		t.Fatalf("Could not read board from %v: %v", fumen, err)
+	}
+	if !reflect.DeepEqual(board, newBoard) {
+		t.Fatalf("Board differs after round trip (old: %v, new: %v)",
+			board, newBoard)
+	}
+}
+
+// Every page of an exported replay should hold the field the previous page
+// left behind once its piece locked, or the pages were placed wrongly.
+func TestFumenReplayPages(t *testing.T) {
+	rand := rand.New(rand.NewSource(7))
+	moves := []Action{MoveLeft, MoveRight, RotateCW, RotateCCW, ToggleSuper}
+
+	actions := make([]ReplayAction, 0)
+	for frame := int64(0); frame < 3000; frame += 10 {
+		act := HardDrop
+		if rand.Float64() < 0.7 {
+			act = moves[rand.Intn(len(moves))]
+		}
+		actions = append(actions, ReplayAction{Action: act, Frame: frame})
+	}
+
+	repData := ReplayData{
+		Seed:              7,
+		TetrisSettings:    DefaultTetrisSettings,
+		ObjectiveID:       Endless,
+		ObjectiveSettings: &EndlessSettings{},
+		Actions:           actions,
+	}
+
+	pages := repData.FumenPages()
+	if len(pages) < 10 {
+		t.Fatalf("Expected a page for each piece, got %v pages", len(pages))
+	}
+
+	decoded, err := DecodeFumen(EncodeFumen(pages))
+	if err != nil {
+		t.Fatalf("Could not decode exported replay: %v", err)
+	}
+	if !reflect.DeepEqual(pages, decoded) {
+		t.Fatalf("Pages differ after round trip")
+	}
+
+	for i := 1; i < len(pages); i++ {
+		if !reflect.DeepEqual(pages[i-1].NextField(), pages[i].Field) {
+			t.Fatalf("Page %v doesn't follow from page %v", i+1, i)
+		}
+	}
+}
@@ -1,11 +1,9 @@
 package main
 
 import (
-	"errors"
 	"fmt"
-	"io/fs"
 	"math"
-	"os"
+	"slices"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -16,10 +14,31 @@ const COUNTDOWN_DURATION_SECS = 1.0 + float64(COUNTDOWN_STEPS)
 const COUNTDOWN_SPEED = 1.0
 const RESET_COUNTDOWN_SPEED = 1.0
 
+// How long the finished board stays up before the results are shown
+const RESULTS_DELAY_SECS = 1.5
+
 var COUNTDOWN_TIMER_LEVELS = []rune(
 	" .-*-. ",
 )
 
+type PauseOption int8
+
+const (
+	PauseResume PauseOption = iota
+	PauseRestart
+	PauseRetrySeed
+	PauseSettings
+	PauseQuit
+)
+
+var PAUSE_OPTION_NAMES = []string{
+	"Resume",
+	"Restart",
+	"Retry same seed",
+	"Settings",
+	"Quit",
+}
+
 type GameScene struct {
 	app *App
 	es  *TetrisField
@@ -34,7 +53,114 @@ type GameScene struct {
 	countdownSpeed float64
 	gameStarted    bool
 
-	actions []ReplayAction
+	actions   []ReplayAction
+	checksums []StateChecksum
+	pauses    []PauseSpan
+
+	paused     bool
+	pausedAt   time.Time
+	pauseFocus PauseOption
+
+	// Keep the same seed when resetting
+	fixedSeed bool
+
+	// Position every game starts from, or nil for an empty board
+	board *Board
+	// Scene to go back to when leaving, or nil for the main menu
+	back Scene
+	// Puzzle being played, if any, and whether the last game solved it for
+	// the first time
+	puzzle      *Puzzle
+	firstSolved bool
+	// Daily challenge being played, if any, and whether the last game was
+	// the best attempt at it so far
+	daily     *DailyChallenge
+	dailyBest bool
+
+	// Replay simulated alongside the game, and the frame on which it reached
+	// each line count
+	ghost           *ReplayPlayer
+	ghostLineFrames []int64
+	// Frame on which the player reached their current line count
+	linesFrame int64
+
+	// Replays that couldn't be written to disk, kept so saving can be retried
+	unsaved []*ReplayData
+	// Set once the player has been warned that leaving loses unsaved replays
+	warnedUnsaved bool
+
+	// Name the last replay was saved under
+	lastSaved string
+
+	// Replay of the game that just ended, waiting for its results to be shown
+	finished     *ReplayData
+	resultsTimer float64
+
+	records  *RecordBook
+	category string
+	// Best result in the category when the game started, if any
+	best *Record
+	// Where the last game placed in the records, or -1 if it didn't
+	recordRank int
+}
+
+type GameSceneOption func(gs *GameScene) *GameScene
+
+// WithSeed starts the game, and every reset of it, with the given seed.
+func WithSeed(seed int64) GameSceneOption {
+	return func(gs *GameScene) *GameScene {
+		gs.seed = seed
+		gs.fixedSeed = true
+		return gs
+	}
+}
+
+// WithBoard starts the game, and every reset of it, from the given board.
+// Games started from a board don't count towards the records.
+func WithBoard(b *Board) GameSceneOption {
+	return func(gs *GameScene) *GameScene {
+		gs.board = b
+		return gs
+	}
+}
+
+// WithPuzzle starts the game from a puzzle's board, and marks the puzzle as
+// solved once its goal is reached.
+func WithPuzzle(p *Puzzle) GameSceneOption {
+	return func(gs *GameScene) *GameScene {
+		gs.board = p.Board
+		gs.puzzle = p
+		return gs
+	}
+}
+
+// WithDaily plays a daily challenge, recording each attempt at it. The
+// objective and settings should be the challenge's own.
+func WithDaily(dc DailyChallenge) GameSceneOption {
+	return func(gs *GameScene) *GameScene {
+		gs.daily = &dc
+		gs.seed = dc.Seed
+		gs.fixedSeed = true
+		return gs
+	}
+}
+
+// WithBack returns to the given scene instead of the main menu when the
+// player leaves.
+func WithBack(back Scene) GameSceneOption {
+	return func(gs *GameScene) *GameScene {
+		gs.back = back
+		return gs
+	}
+}
+
+// WithGhost races the player against the given replay.
+func WithGhost(ghost ReplayData) GameSceneOption {
+	return func(gs *GameScene) *GameScene {
+		gs.ghost = NewReplayPlayer(ghost)
+		gs.ghostLineFrames = ghost.LineFrames()
+		return gs
+	}
 }
 
 func (gs *GameScene) Init(
@@ -42,11 +168,19 @@ func (gs *GameScene) Init(
 	globalSettings GlobalTetrisSettings,
 	objectiveID ObjectiveID,
 	objectiveSettings ObjectiveSettings,
+	options ...GameSceneOption,
 ) {
 	gs.app = app
 	gs.seed = time.Now().UnixNano()
+	for _, opt := range options {
+		gs = opt(gs)
+	}
+
 	gs.es = NewTetrisField(gs.seed, globalSettings)
 	gs.es.RegisterAudio(gs.app.Audio)
+	if gs.board != nil {
+		gs.es.LoadBoard(gs.board)
+	}
 
 	gs.globalSettings = globalSettings
 	gs.objectiveID = objectiveID
@@ -58,6 +192,90 @@ func (gs *GameScene) Init(
 	gs.gameStarted = false
 
 	gs.actions = make([]ReplayAction, 0)
+	gs.checksums = make([]StateChecksum, 0)
+	gs.pauses = make([]PauseSpan, 0)
+
+	gs.AddHandlers()
+	gs.LoadRecords()
+}
+
+// InitFromReplay continues a game from the current state of a replay. The
+// actions performed so far are kept, so the resulting replay contains the
+// whole game.
+func (gs *GameScene) InitFromReplay(app *App, player *ReplayPlayer) {
+	gs.app = app
+	gs.seed = player.Data.Seed
+	gs.board = player.Data.Board
+	gs.es = player.Field
+	gs.es.RegisterAudio(gs.app.Audio)
+
+	gs.globalSettings = player.Data.TetrisSettings
+	gs.objectiveID = player.Data.ObjectiveID
+	gs.objectiveSettings = player.Data.ObjectiveSettings
+	gs.objective = player.Objective
+
+	gs.countdownTimer = COUNTDOWN_DURATION_SECS
+	gs.countdownSpeed = COUNTDOWN_SPEED
+	gs.gameStarted = false
+
+	gs.actions = make([]ReplayAction, player.actionPointer)
+	copy(gs.actions, player.Data.Actions[:player.actionPointer])
+	gs.checksums = slices.Clone(player.Checksums)
+	gs.pauses = slices.DeleteFunc(
+		slices.Clone(player.Data.Pauses),
+		func(ps PauseSpan) bool {
+			return ps.Frame > player.Frame()
+		},
+	)
+
+	gs.AddHandlers()
+	gs.LoadRecords()
+}
+
+// LoadRecords reads the records for the game's objective and settings. The
+// game can still be played without them.
+func (gs *GameScene) LoadRecords() {
+	gs.category = ObjectiveCategory(gs.objectiveID, gs.objectiveSettings)
+
+	records, err := LoadRecordBook()
+	if err != nil {
+		gs.app.ReportError("Could not load records", err)
+	}
+	gs.records = records
+	gs.UpdateBest()
+}
+
+// UpdateBest remembers the current best result, for the next game to be
+// compared against.
+func (gs *GameScene) UpdateBest() {
+	gs.best = nil
+	if gs.board != nil {
+		return
+	}
+	if best, ok := gs.records.Best(gs.category); ok {
+		gs.best = &best
+	}
+}
+
+// BeatingBest reports whether the game so far is better than the best result
+// there was when it started.
+func (gs *GameScene) BeatingBest() bool {
+	ot, ok := GetObjectiveType(gs.objectiveID)
+	if !ok || gs.best == nil {
+		return false
+	}
+
+	summary := SummarizeField(gs.objectiveID, gs.objectiveSettings, gs.es)
+	return ot.Qualifies(summary) && ot.Better(summary, gs.best.Summary)
+}
+
+func (gs *GameScene) AddHandlers() {
+	gs.linesFrame = 0
+	gs.es.AddLineClearHandler(func(garbage, nonGarbage int) {
+		if garbage+nonGarbage > 0 {
+			gs.linesFrame = gs.es.frameCount
+		}
+	})
 
 	gs.es.AddGameOverHandler(func(failed bool, reason string) {
 		gs.OnGameOver(failed, reason)
@@ -65,27 +283,39 @@ func (gs *GameScene) Init(
 }
 
 func (gs *GameScene) HandleEvent(ev tcell.Event) {
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		if len(gs.unsaved) > 0 && (IsRune(ev, 's') || IsRune(ev, 'S')) {
+			gs.SaveReplays()
+		}
+	case *tcell.EventFocus:
+		// Pause when the terminal loses focus
+		if !ev.Focused {
+			gs.Pause()
+		}
+	}
 }
 
 func (gs *GameScene) HandleAction(act Action) {
+	if gs.paused {
+		gs.HandlePauseAction(act)
+		return
+	}
+
+	if gs.finished != nil && act == MenuConfirm {
+		gs.ShowResults()
+		return
+	}
+
 	switch act {
 	case Quit:
-		gs.app.OpenMenuScene()
+		gs.Quit()
 	case Reset:
-		// gs.app.Audio.StopSound("seelremix")
-		gs.seed = time.Now().UnixNano()
-		gs.es.HandleReset(gs.seed)
-		gs.objective = gs.objectiveSettings.Init(gs.es)
-
-		gs.countdownTimer = COUNTDOWN_DURATION_SECS
-		gs.gameStarted = false
-		gs.countdownSpeed = RESET_COUNTDOWN_SPEED
-
-		gs.actions = make([]ReplayAction, 0)
-
-		gs.es.AddGameOverHandler(func(failed bool, reason string) {
-			gs.OnGameOver(failed, reason)
-		})
+		gs.Restart()
+	case RetrySeed:
+		gs.RetrySeed()
+	case Pause:
+		gs.Pause()
 	default:
 		if gs.gameStarted {
 			gs.actions = append(gs.actions, ReplayAction{
@@ -97,13 +327,122 @@ func (gs *GameScene) HandleAction(act Action) {
 	}
 }
 
+func (gs *GameScene) HandlePauseAction(act Action) {
+	switch act {
+	case MoveUp:
+		gs.pauseFocus = max(0, gs.pauseFocus-1)
+	case MoveDown:
+		gs.pauseFocus = min(PauseOption(len(PAUSE_OPTION_NAMES)-1), gs.pauseFocus+1)
+	case Pause:
+		gs.Resume()
+	case Quit:
+		gs.Quit()
+	case MenuConfirm:
+		switch gs.pauseFocus {
+		case PauseResume:
+			gs.Resume()
+		case PauseRestart:
+			gs.Resume()
+			gs.Restart()
+		case PauseRetrySeed:
+			gs.Resume()
+			gs.RetrySeed()
+		case PauseSettings:
+			gs.app.OpenOptionsScene(gs)
+		case PauseQuit:
+			gs.Quit()
+		}
+	}
+}
+
+// Pause stops the game until it is resumed. Only a game in progress can be
+// paused.
+func (gs *GameScene) Pause() {
+	if gs.paused || !gs.gameStarted || gs.es.gameOver {
+		return
+	}
+
+	gs.paused = true
+	gs.pausedAt = time.Now()
+	gs.pauseFocus = PauseResume
+}
+
+// Resume continues the game and records how long it was paused for.
+func (gs *GameScene) Resume() {
+	if !gs.paused {
+		return
+	}
+
+	gs.paused = false
+	gs.pauses = append(gs.pauses, PauseSpan{
+		Frame:  gs.es.frameCount,
+		Millis: time.Since(gs.pausedAt).Milliseconds(),
+	})
+}
+
+func (gs *GameScene) Restart() {
+	if !gs.fixedSeed {
+		gs.seed = time.Now().UnixNano()
+	}
+	gs.RetrySeed()
+}
+
+// RetrySeed starts the game again with the same pieces as the last one.
+func (gs *GameScene) RetrySeed() {
+	// gs.app.Audio.StopSound("seelremix")
+	gs.es.HandleReset(gs.seed)
+	if gs.board != nil {
+		gs.es.LoadBoard(gs.board)
+	}
+	gs.objective = gs.objectiveSettings.Init(gs.es)
+
+	gs.countdownTimer = COUNTDOWN_DURATION_SECS
+	gs.gameStarted = false
+	gs.countdownSpeed = RESET_COUNTDOWN_SPEED
+
+	gs.actions = make([]ReplayAction, 0)
+	gs.checksums = make([]StateChecksum, 0)
+	gs.pauses = make([]PauseSpan, 0)
+	gs.finished = nil
+	gs.UpdateBest()
+
+	gs.AddHandlers()
+
+	if gs.ghost != nil {
+		gs.ghost.Reset()
+	}
+}
+
+// Quit goes back to where the game was started from, warning first if there are replays that
+// haven't been saved.
+func (gs *GameScene) Quit() {
+	if len(gs.unsaved) > 0 && !gs.warnedUnsaved {
+		gs.warnedUnsaved = true
+		gs.app.ShowToast("Replay not saved: press s to retry, or quit again to discard it")
+		return
+	}
+	if gs.back != nil {
+		gs.app.NextScene = gs.back
+		return
+	}
+	gs.app.OpenMenuScene()
+}
+
 func (gs *GameScene) Update() {
+	if gs.paused {
+		return
+	}
+
 	if !gs.gameStarted {
 		gs.countdownTimer -= (UPDATE_TICK_RATE_MS / 1000.0) * gs.countdownSpeed
 		if gs.countdownTimer < 0 {
 			gs.gameStarted = true
-			gs.es.gameStarted = true
-			gs.es.GetRandomPiece()
+
+			// A game taken over from a replay already has a piece in play
+			if !gs.es.gameStarted {
+				gs.es.gameStarted = true
+				gs.es.GetRandomPiece()
+			}
 
 			// gs.app.Audio.PlaySound("seelremix")
 		}
@@ -111,16 +450,41 @@ func (gs *GameScene) Update() {
 		return
 	}
 
+	if gs.finished != nil {
+		gs.resultsTimer -= UPDATE_TICK_RATE_MS / 1000.0
+		if gs.resultsTimer <= 0 {
+			gs.ShowResults()
+			return
+		}
+	}
+
 	gs.objective.Update(gs.es)
+	gs.checksums = AppendChecksum(gs.checksums, gs.es)
+
+	if gs.ghost != nil && !gs.ghost.Done() {
+		gs.ghost.Step()
+	}
 }
 
 func (gs *GameScene) OnGameOver(failed bool, reason string) {
-	replayData := ReplayData{
+	gs.firstSolved = false
+	if gs.puzzle != nil && !failed {
+		gs.MarkPuzzleSolved()
+	}
+
+	if ot, ok := GetObjectiveType(gs.objectiveID); ok && ot.NoReplays {
+		return
+	}
+
+	replayData := &ReplayData{
 		Seed:              gs.seed,
 		TetrisSettings:    gs.globalSettings,
 		ObjectiveID:       gs.objectiveID,
 		ObjectiveSettings: gs.objectiveSettings,
 		Actions:           gs.actions,
+		Checksums:         gs.checksums,
+		Pauses:            gs.pauses,
+		Board:             gs.board,
 	}
 
 	gs.app.Logger.Printf("Seed: %v\n", gs.seed)
@@ -129,20 +493,115 @@ func (gs *GameScene) OnGameOver(failed bool, reason string) {
 	gs.app.Logger.Printf("ObjectiveSettings: %v\n", gs.objectiveSettings)
 	gs.app.Logger.Printf("Number of actions: %v\n", len(gs.actions))
 
-	err := os.Mkdir("replays", 0755)
-	if err != nil && !errors.Is(err, fs.ErrExist) {
-		panic(err)
+	gs.unsaved = append(gs.unsaved, replayData)
+	gs.SaveReplays()
+	gs.AddRecord(replayData)
+	if gs.daily != nil {
+		gs.AddDailyResult(replayData)
+	}
+
+	gs.finished = replayData
+	gs.resultsTimer = RESULTS_DELAY_SECS
+}
+
+// MarkPuzzleSolved saves that the puzzle being played has been solved.
+func (gs *GameScene) MarkPuzzleSolved() {
+	progress, err := LoadPuzzleProgress()
+	if err != nil {
+		gs.app.ReportError("Could not load puzzle progress", err)
+		return
+	}
+
+	if !progress.MarkSolved(gs.puzzle.Name) {
+		return
+	}
+	gs.firstSolved = true
+
+	err = progress.Save()
+	if err != nil {
+		gs.app.ReportError("Could not save puzzle progress", err)
+	}
+}
+
+// AddRecord enters the finished game into the records, linked to its replay
+// if that was saved. Games started from a board aren't comparable with the
+// rest, so they're left out.
+func (gs *GameScene) AddRecord(replayData *ReplayData) {
+	if gs.board != nil {
+		gs.recordRank = -1
+		return
+	}
+
+	rec := Record{
+		Summary: SummarizeField(gs.objectiveID, gs.objectiveSettings, gs.es),
+		Date:    time.Now(),
+	}
+	if !slices.Contains(gs.unsaved, replayData) {
+		rec.Replay = gs.lastSaved
 	}
 
-	file, err := os.Create(fmt.Sprintf("replays/rp-%v", time.Now()))
+	gs.recordRank = gs.records.Add(rec)
+	if gs.recordRank < 0 {
+		return
+	}
+	err := gs.records.Save()
 	if err != nil {
-		panic(err)
+		gs.app.ReportError("Could not save records", err)
 	}
-	defer file.Close()
-	err = StdEncoder(&replayData, file)
+}
+
+// AddDailyResult counts the finished game as an attempt at the daily
+// challenge, linked to its replay if that was saved.
+func (gs *GameScene) AddDailyResult(replayData *ReplayData) {
+	gs.dailyBest = false
+
+	book, err := LoadDailyBook()
 	if err != nil {
-		panic(err)
+		gs.app.ReportError("Could not load daily results", err)
+		return
+	}
+
+	rec := Record{
+		Summary: SummarizeField(gs.objectiveID, gs.objectiveSettings, gs.es),
+		Date:    time.Now(),
+	}
+	if !slices.Contains(gs.unsaved, replayData) {
+		rec.Replay = gs.lastSaved
+	}
+	gs.dailyBest = book.Add(gs.daily.Date, rec)
+
+	err = book.Save()
+	if err != nil {
+		gs.app.ReportError("Could not save daily results", err)
+	}
+}
+
+// ShowResults leaves the finished board for the results screen.
+func (gs *GameScene) ShowResults() {
+	if gs.finished == nil {
+		return
+	}
+	gs.app.OpenResultsScene(gs, gs.finished)
+	gs.finished = nil
+}
+
+// SaveReplays writes out every replay that hasn't been saved yet. The ones
+// that fail are kept for another attempt.
+func (gs *GameScene) SaveReplays() {
+	failed := gs.unsaved[:0]
+	for _, replayData := range gs.unsaved {
+		name, err := SaveReplay(replayData)
+		if err != nil {
+			gs.app.ReportError("Could not save replay", err)
+			failed = append(failed, replayData)
+			continue
+		}
+		gs.app.Logger.Printf("Saved replay %v\n", name)
+		gs.lastSaved = name
 	}
+
+	gs.unsaved = failed
+	gs.warnedUnsaved = false
 }
 
 func (gs *GameScene) Draw(sw, sh int, rr Area, lag float64) {
@@ -150,8 +609,43 @@ func (gs *GameScene) Draw(sw, sh int, rr Area, lag float64) {
 	anchorX := playingField.X - 2
 	anchorY := playingField.Bottom() - 2
 
-	gs.es.Draw(sw, sh, playingField, lag)
-	DrawStats(gs.objective.GetStats(), anchorX, anchorY)
+	if gs.paused {
+		gs.DrawPauseMenu(playingField)
+	} else {
+		gs.es.Draw(sw, sh, playingField, lag)
+	}
+
+	stats := gs.objective.GetStats()
+	if gs.ghost != nil && !gs.paused {
+		stats = append(slices.Clip(stats), gs.GhostStat())
+
+		SetCenteredString(rr.Right()-7, playingField.Y+1, "GHOST", defStyle)
+		gs.ghost.Field.DrawGhost(Area{
+			X:      rr.Right() - 12,
+			Y:      playingField.Y + 2,
+			Width:  BOARD_WIDTH,
+			Height: BOARD_HEIGHT,
+		})
+	}
+	DrawStats(stats, anchorX, anchorY)
+
+	if len(gs.unsaved) > 0 {
+		SetString(
+			rr.X,
+			rr.Y,
+			"Replay not saved - s:retry",
+			defStyle.Foreground(tcell.ColorRed),
+		)
+	}
+
+	if gs.BeatingBest() {
+		SetString(
+			rr.X,
+			rr.Y+1,
+			"NEW PB!",
+			defStyle.Bold(true).Foreground(tcell.ColorYellow),
+		)
+	}
 
 	if !gs.gameStarted {
 		textAnchorX := playingField.X + BOARD_WIDTH/2
@@ -175,6 +669,56 @@ func (gs *GameScene) Draw(sw, sh int, rr Area, lag float64) {
 	}
 }
 
+// GhostStat compares the player against the ghost, both by how long it took
+// to reach the current line count and by score.
+func (gs *GameScene) GhostStat() Stat {
+	return Stat{
+		Compute: func() []string {
+			pace := "-"
+			lines := gs.es.lines
+			if lines > 0 && lines < int64(len(gs.ghostLineFrames)) {
+				delta := gs.linesFrame - gs.ghostLineFrames[lines]
+				pace = fmt.Sprintf(
+					"%+.2fs @%dL",
+					float64(delta)/float64(FRAMES_PER_SECOND),
+					lines,
+				)
+			}
+
+			return []string{
+				"GHOST",
+				pace,
+				fmt.Sprintf("%+d pts", gs.es.score-gs.ghost.Field.score),
+			}
+		},
+	}
+}
+
+// DrawPauseMenu draws an empty well in place of the board, so the paused game
+// can't be studied, with the pause options inside it.
+func (gs *GameScene) DrawPauseMenu(playingField Area) {
+	gameArea := Area{
+		X:      playingField.X,
+		Y:      playingField.Y + 2,
+		Width:  BOARD_WIDTH,
+		Height: BOARD_HEIGHT,
+	}
+	gs.es.DrawWell(gameArea)
+
+	centerX := gameArea.X + BOARD_WIDTH/2
+	y := gameArea.Y + 4
+	SetCenteredString(centerX, y, "PAUSED", defStyle.Bold(true))
+	y += 3
+
+	for i, name := range PAUSE_OPTION_NAMES {
+		style := defStyle
+		if PauseOption(i) == gs.pauseFocus {
+			style = style.Reverse(true)
+		}
+		SetCenteredString(centerX, y+2*i, name, style)
+	}
+}
+
 func (gs *GameScene) DrawProgressBar(anchorX, anchorY int, value float64) {
 	for i := 0; i < BOARD_WIDTH; i++ {
 		intensity := value*10 - float64(i)
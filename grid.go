@@ -1,5 +1,7 @@
 package main
 
+import "slices"
+
 type Grid[T any] struct {
 	data   []T
 	Width  int
@@ -80,6 +82,14 @@ func GridFromStrings(strings ...string) Grid[rune] {
 	return GridFromSlices(slices...)
 }
 
+func (g *Grid[T]) Clone() Grid[T] {
+	return Grid[T]{
+		data:   slices.Clone(g.data),
+		Width:  g.Width,
+		Height: g.Height,
+	}
+}
+
 func (g *Grid[T]) InBounds(x, y int) bool {
 	return x >= 0 && x < g.Width && y >= 0 && y < g.Height
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// KeyBinding is a key that triggers an action. Printable keys are matched by
+// rune, everything else by key code.
+type KeyBinding struct {
+	Key  tcell.Key
+	Rune rune
+}
+
+func RuneBinding(r rune) KeyBinding {
+	return KeyBinding{Key: tcell.KeyRune, Rune: r}
+}
+
+func KeyCodeBinding(key tcell.Key) KeyBinding {
+	return KeyBinding{Key: key}
+}
+
+var DefaultKeyBindings = map[Action][]KeyBinding{
+	MoveLeft:    {KeyCodeBinding(tcell.KeyLeft)},
+	MoveRight:   {KeyCodeBinding(tcell.KeyRight)},
+	MoveUp:      {KeyCodeBinding(tcell.KeyUp)},
+	MoveDown:    {KeyCodeBinding(tcell.KeyDown)},
+	MenuConfirm: {KeyCodeBinding(tcell.KeyEnter)},
+
+	HardDrop:    {RuneBinding(' ')},
+	ToggleSuper: {RuneBinding('f'), RuneBinding('F')},
+
+	RotateCCW:     {RuneBinding('z'), RuneBinding('Z')},
+	RotateCW:      {RuneBinding('x'), RuneBinding('X')},
+	SwapHoldPiece: {RuneBinding('c'), RuneBinding('C')},
+
+	Quit:      {RuneBinding('q'), RuneBinding('Q')},
+	Reset:     {RuneBinding('r'), RuneBinding('R')},
+	RetrySeed: {RuneBinding('t'), RuneBinding('T')},
+	Pause:     {RuneBinding('p'), RuneBinding('P')},
+
+	Exit: {KeyCodeBinding(tcell.KeyEscape), KeyCodeBinding(tcell.KeyCtrlC)},
+
+	PracticeUndo:    {RuneBinding('u'), RuneBinding('U')},
+	PracticeRedo:    {RuneBinding('y'), RuneBinding('Y')},
+	PracticeMark:    {RuneBinding('m'), RuneBinding('M')},
+	PracticeLoad:    {RuneBinding('l'), RuneBinding('L')},
+	PracticeReroll:  {RuneBinding('n'), RuneBinding('N')},
+	PracticeGravity: {RuneBinding('g'), RuneBinding('G')},
+
+	QueueI: {RuneBinding('1')},
+	QueueJ: {RuneBinding('2')},
+	QueueL: {RuneBinding('3')},
+	QueueO: {RuneBinding('4')},
+	QueueS: {RuneBinding('5')},
+	QueueT: {RuneBinding('6')},
+	QueueZ: {RuneBinding('7')},
+}
+
+// KeyBindingFromEvent returns the binding that matches a key press.
+func KeyBindingFromEvent(ev *tcell.EventKey) KeyBinding {
+	if ev.Key() == tcell.KeyRune {
+		return RuneBinding(ev.Rune())
+	}
+	return KeyCodeBinding(ev.Key())
+}
+
+func (kb KeyBinding) String() string {
+	if kb.Key == tcell.KeyRune {
+		if kb.Rune == ' ' {
+			return "Space"
+		}
+		return string(kb.Rune)
+	}
+
+	if name, ok := tcell.KeyNames[kb.Key]; ok {
+		return name
+	}
+	return fmt.Sprintf("Key[%d]", kb.Key)
+}
+
+// ParseKeyBinding reads a binding written by String: a single character, or
+// the name of a special key such as "Left" or "Enter".
+func ParseKeyBinding(name string) (KeyBinding, error) {
+	if name == "Space" {
+		return RuneBinding(' '), nil
+	}
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		return RuneBinding(r), nil
+	}
+
+	for key, keyName := range tcell.KeyNames {
+		if keyName == name && key != tcell.KeyRune {
+			return KeyCodeBinding(key), nil
+		}
+	}
+	return KeyBinding{}, fmt.Errorf("Invalid key %q", name)
+}
+
+func (kb KeyBinding) MarshalText() ([]byte, error) {
+	return []byte(kb.String()), nil
+}
+
+func (kb *KeyBinding) UnmarshalText(text []byte) error {
+	binding, err := ParseKeyBinding(string(text))
+	if err != nil {
+		return err
+	}
+	*kb = binding
+	return nil
+}
+
+// MergeDefaultBindings fills in the default keys of actions that the saved
+// bindings don't mention, such as ones added since they were saved. A
+// default key that the player has already bound to something else is left
+// out, so it keeps doing what they chose.
+func MergeDefaultBindings(saved map[Action][]KeyBinding) map[Action][]KeyBinding {
+	taken := make(map[KeyBinding]bool)
+	for _, keys := range saved {
+		for _, key := range keys {
+			taken[key] = true
+		}
+	}
+
+	for act, keys := range DefaultKeyBindings {
+		if _, ok := saved[act]; ok {
+			continue
+		}
+		merged := make([]KeyBinding, 0, len(keys))
+		for _, key := range keys {
+			if !taken[key] {
+				merged = append(merged, key)
+			}
+		}
+		saved[act] = merged
+	}
+	return saved
+}
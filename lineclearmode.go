@@ -18,6 +18,7 @@ func (lcs *LineClearSettings) Init(es *TetrisField) Objective {
 			CreateElapsedTimeStat(es),
 			CreateLinesRemainingStat(es, lcs.Lines),
 			CreatePiecesStat(es),
+			CreateFinesseStat(es),
 		},
 	}
 }
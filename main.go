@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/gdamore/tcell/v2"
 )
 
@@ -12,6 +14,10 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(RunCommand(os.Args[1:]))
+	}
+
 	a := NewApp()
 	defer a.Quit()
 	a.Loop()
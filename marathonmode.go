@@ -0,0 +1,163 @@
+package main
+
+import "fmt"
+
+// Frames a piece takes to fall one row at each level under classic rules,
+// following the NES speed curve. Levels past the end of the table stay at
+// its last speed.
+var CLASSIC_FRAMES_PER_ROW = []int64{
+	48, 43, 38, 33, 28, 23, 18, 13, 8, 6,
+	5, 5, 5, 4, 4, 4, 3, 3, 3,
+	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
+	1,
+}
+
+type MarathonSettings struct {
+	// Lines to clear, or 0 to play on without a target
+	Lines int64
+	// Level that ends the game once reached, or 0 for none
+	LevelCap int64
+	// No hold, no hard drop, one preview, no ghost, and the NES speed curve
+	Classic bool
+}
+
+// MarathonObjective is played until the line target or level cap is
+// reached, for the highest score. The game speeds up with every level.
+type MarathonObjective struct {
+	MarathonSettings
+
+	stats []Stat
+}
+
+func (ms *MarathonSettings) Init(es *TetrisField) Objective {
+	mo := &MarathonObjective{
+		MarathonSettings: *ms,
+	}
+
+	linesStat := CreateLinesStat(es)
+	if ms.Lines > 0 {
+		linesStat = CreateLinesRemainingStat(es, ms.Lines)
+	}
+	mo.stats = []Stat{
+		CreateElapsedTimeStat(es),
+		linesStat,
+		CreatePiecesStat(es),
+		mo.MarathonStat(es),
+	}
+
+	if ms.Classic {
+		es.previews = 1
+		es.hideGhost = true
+		es.fallRate = ClassicFallRate(es.level)
+	}
+
+	es.AddLineClearHandler(func(garbage, nonGarbage int) {
+		if garbage+nonGarbage == 0 {
+			return
+		}
+		if mo.Classic {
+			es.fallRate = ClassicFallRate(es.level)
+		}
+		mo.CheckGoal(es)
+	})
+
+	return mo
+}
+
+// Validate rejects a level cap the game would start at or past.
+func (ms *MarathonSettings) Validate(ts GlobalTetrisSettings) error {
+	if ms.LevelCap > 0 && ts.StartingLevel >= ms.LevelCap {
+		return fmt.Errorf(
+			"Starting level must be below the level cap of %d", ms.LevelCap)
+	}
+	return nil
+}
+
+// ClassicFallRate returns how fast pieces fall at a level under classic
+// rules, in the units of TetrisField.fallRate.
+func ClassicFallRate(level int64) int64 {
+	idx := min(max(level-1, 0), int64(len(CLASSIC_FRAMES_PER_ROW)-1))
+	frames := CLASSIC_FRAMES_PER_ROW[idx]
+	return (BASE_GRAVITY_UNIT + frames - 1) / frames
+}
+
+// CheckGoal ends the game once the line target or the level cap is reached.
+func (mo *MarathonObjective) CheckGoal(es *TetrisField) {
+	if es.gameOver {
+		return
+	}
+
+	if mo.Lines > 0 && es.lines >= mo.Lines {
+		es.ObjectiveComplete("Cleared all lines")
+	} else if mo.LevelCap > 0 && es.level >= mo.LevelCap {
+		es.ObjectiveComplete(fmt.Sprintf("Reached level %d", mo.LevelCap))
+	}
+}
+
+func (mo *MarathonObjective) GetStats() []Stat {
+	return mo.stats
+}
+
+func (mo *MarathonObjective) Update(es *TetrisField) {
+	if es.gameOver {
+		return
+	}
+	es.Update()
+}
+
+func (mo *MarathonObjective) HandleAction(act Action, es *TetrisField) {
+	// Dashing can drop a piece straight down, so it goes with hard drops
+	if mo.Classic && (act == SwapHoldPiece || act == HardDrop || act == ToggleSuper) {
+		return
+	}
+	es.HandleAction(act)
+}
+
+func (mo *MarathonObjective) MarathonStat(es *TetrisField) Stat {
+	return Stat{
+		Compute: func() []string {
+			level := fmt.Sprintf("level %d", es.level)
+			if mo.LevelCap > 0 {
+				level = fmt.Sprintf("level %d/%d", es.level, mo.LevelCap)
+			}
+			rules := "modern"
+			if mo.Classic {
+				rules = "classic"
+			}
+
+			return []string{
+				"MARATHON",
+				level,
+				rules,
+			}
+		},
+	}
+}
+
+func (ms *MarathonSettings) CreateFormFields() []FormField {
+	return []FormField{
+		NewIntegerField(
+			"Lines (0 for no target)",
+			ms.Lines,
+			func(value int64) {
+				ms.Lines = value
+			},
+			WithMin(0),
+		),
+		NewIntegerField(
+			"Level cap (0 for none)",
+			ms.LevelCap,
+			func(value int64) {
+				ms.LevelCap = value
+			},
+			WithMin(0),
+		),
+		NewBooleanField(
+			"Classic rules",
+			ms.Classic,
+			func(value bool) {
+				ms.Classic = value
+			},
+		),
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import "testing"
+
+func TestClassicFallRate(t *testing.T) {
+	for _, tc := range []struct {
+		level int64
+		rate  int64
+	}{
+		// 48 frames a row, rounded up so pieces never fall slower
+		{1, 3},
+		{0, 3},
+		{2, 3},
+		{9, 16},
+		{10, 22},
+		{19, 43},
+		{20, 64},
+		{29, 64},
+		{30, 128},
+		{99, 128},
+	} {
+		if rate := ClassicFallRate(tc.level); rate != tc.rate {
+			t.Errorf("Level %d falls at %d, want %d", tc.level, rate, tc.rate)
+		}
+	}
+}
+
+func TestMarathonCheckGoal(t *testing.T) {
+	for _, tc := range []struct {
+		settings MarathonSettings
+		lines    int64
+		level    int64
+		reason   string
+	}{
+		{MarathonSettings{Lines: 150}, 149, 15, ""},
+		{MarathonSettings{Lines: 150}, 152, 16, "Cleared all lines"},
+		{MarathonSettings{LevelCap: 15}, 139, 14, ""},
+		{MarathonSettings{LevelCap: 15}, 140, 15, "Reached level 15"},
+		{MarathonSettings{Lines: 150, LevelCap: 10}, 90, 10, "Reached level 10"},
+		{MarathonSettings{}, 999, 100, ""},
+	} {
+		es := NewTetrisField(1, DefaultTetrisSettings)
+		mo := tc.settings.Init(es).(*MarathonObjective)
+		es.lines, es.level = tc.lines, tc.level
+		mo.CheckGoal(es)
+
+		if es.gameOver != (tc.reason != "") || es.gameOverReason != tc.reason {
+			t.Errorf("%+v at %d lines, level %d: over %v %q, want %q",
+				tc.settings, tc.lines, tc.level, es.gameOver, es.gameOverReason, tc.reason)
+		}
+	}
+}
+
+func TestMarathonValidate(t *testing.T) {
+	ts := DefaultTetrisSettings
+	ts.StartingLevel = 10
+
+	for _, tc := range []struct {
+		levelCap int64
+		ok       bool
+	}{
+		{0, true},
+		{11, true},
+		{10, false},
+		{5, false},
+	} {
+		ms := &MarathonSettings{LevelCap: tc.levelCap}
+		if err := ms.Validate(ts); (err == nil) != tc.ok {
+			t.Errorf("Cap %d from level 10: error %v", tc.levelCap, err)
+		}
+	}
+}
+
+func TestMarathonClassic(t *testing.T) {
+	es := NewTetrisField(1, DefaultTetrisSettings)
+	mo := (&MarathonSettings{Classic: true}).Init(es)
+	if es.previews != 1 || !es.hideGhost || es.fallRate != ClassicFallRate(1) {
+		t.Errorf("Classic rules set %d previews, ghost hidden %v, fall rate %d",
+			es.previews, es.hideGhost, es.fallRate)
+	}
+
+	es.GetRandomPiece()
+	held := es.cpIdx
+	mo.HandleAction(SwapHoldPiece, es)
+	mo.HandleAction(HardDrop, es)
+	if es.holdPiece != NO_HOLD_PIECE || es.cpIdx != held || es.pieceCount != 0 {
+		t.Errorf("Hold or hard drop went through under classic rules")
+	}
+}
@@ -2,25 +2,89 @@ package main
 
 import "github.com/gdamore/tcell/v2"
 
-var MENU_OPTIONS = []string{
-	"Sprint",
-	"Endless",
-	"Survival",
-	"Cheese",
-	"Score Attack",
-	"Replays",
-	"Credits",
-	"Quit",
+type MenuOption struct {
+	Name   string
+	Select func()
 }
 
 type MenuScene struct {
 	app *App
 
+	options   []MenuOption
 	menuFocus int
 }
 
 func (ms *MenuScene) Init(app *App) {
 	ms.app = app
+
+	ms.options = make([]MenuOption, 0, len(ObjectiveTypes)+9)
+	for _, ot := range MenuObjectiveTypes() {
+		ms.options = append(ms.options, MenuOption{
+			Name: ot.Name,
+			Select: func() {
+				ms.app.OpenPreGameScene(
+					ms.app.Settings.TetrisSettingsFor(ot.ID),
+					ot.ID,
+					ms.app.Settings.ObjectiveSettingsFor(ot),
+				)
+			},
+		})
+	}
+
+	ms.options = append(ms.options,
+		MenuOption{
+			Name: "Puzzles",
+			Select: func() {
+				ms.app.OpenPuzzleScene()
+			},
+		},
+		MenuOption{
+			Name: "Daily",
+			Select: func() {
+				ms.app.OpenDailyScene()
+			},
+		},
+		MenuOption{
+			Name: "Replays",
+			Select: func() {
+				ms.app.OpenReplayBrowserScene()
+			},
+		},
+		MenuOption{
+			Name: "Records",
+			Select: func() {
+				ms.app.OpenRecordsScene()
+			},
+		},
+		MenuOption{
+			Name: "Editor",
+			Select: func() {
+				ms.app.OpenEditorScene()
+			},
+		},
+		MenuOption{
+			Name: "Controls",
+			Select: func() {
+				ms.app.OpenControlsScene()
+			},
+		},
+		MenuOption{
+			Name: "Options",
+			Select: func() {
+				ms.app.OpenOptionsScene(nil)
+			},
+		},
+		MenuOption{
+			Name:   "Credits",
+			Select: func() {},
+		},
+		MenuOption{
+			Name: "Quit",
+			Select: func() {
+				ms.app.WillQuit = true
+			},
+		},
+	)
 }
 
 func (ms *MenuScene) HandleEvent(ev tcell.Event) {
@@ -31,7 +95,7 @@ func (ms *MenuScene) HandleAction(act Action) {
 	case MoveUp:
 		ms.menuFocus = max(0, ms.menuFocus-1)
 	case MoveDown:
-		ms.menuFocus = min(len(MENU_OPTIONS), ms.menuFocus+1)
+		ms.menuFocus = min(len(ms.options)-1, ms.menuFocus+1)
 	case MenuConfirm:
 		ms.ConfirmAction()
 	case Quit:
@@ -43,52 +107,7 @@ func (ms *MenuScene) Update() {
 }
 
 func (ms *MenuScene) ConfirmAction() {
-	switch ms.menuFocus {
-	case 0:
-		ms.app.OpenPreGameScene(
-			DefaultTetrisSettings,
-			LineClear,
-			&LineClearSettings{
-				Lines: 40,
-			},
-		)
-	case 1:
-		ms.app.OpenPreGameScene(
-			DefaultTetrisSettings,
-			Endless,
-			&EndlessSettings{},
-		)
-	case 2:
-		ms.app.OpenPreGameScene(
-			DefaultTetrisSettings,
-			Survival,
-			&SurvivalSettings{
-				GarbageRate: 1000,
-			},
-		)
-	case 3:
-		ms.app.OpenPreGameScene(
-			DefaultTetrisSettings,
-			Cheese,
-			&CheeseSettings{
-				Garbage: 18,
-			},
-		)
-	case 4:
-		ms.app.OpenPreGameScene(
-			DefaultTetrisSettings,
-			ScoreAttack,
-			&ScoreAttackSettings{
-				Duration: 120,
-			},
-		)
-	case 5:
-		ms.app.OpenReplayBrowserScene()
-	case 6:
-		break
-	case 7:
-		ms.app.WillQuit = true
-	}
+	ms.options[ms.menuFocus].Select()
 }
 
 func (ms *MenuScene) Draw(sw, sh int, rr Area, lag float64) {
@@ -98,7 +117,7 @@ func (ms *MenuScene) Draw(sw, sh int, rr Area, lag float64) {
 		"Tetris",
 		defStyle)
 
-	for i, opt := range MENU_OPTIONS {
+	for i, opt := range ms.options {
 		style := defStyle
 		if i == ms.menuFocus {
 			style = style.Reverse(true)
@@ -112,7 +131,7 @@ func (ms *MenuScene) Draw(sw, sh int, rr Area, lag float64) {
 		SetString(
 			rr.X+2,
 			rr.Y+2+2*i,
-			opt,
+			opt.Name,
 			style)
 	}
 }
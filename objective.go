@@ -1,6 +1,13 @@
 package main
 
-import "fmt"
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
 
 type GlobalTetrisSettings struct {
 	StartingLevel   int64
@@ -24,6 +31,13 @@ type Objective interface {
 	GetStats() []Stat
 }
 
+// ObjectiveSettingsValidator is implemented by objective settings that only
+// make sense with some game settings. Games aren't started from the form
+// until the two agree.
+type ObjectiveSettingsValidator interface {
+	Validate(ts GlobalTetrisSettings) error
+}
+
 type ObjectiveID int8
 
 const (
@@ -32,6 +46,11 @@ const (
 	Endless
 	Cheese
 	ScoreAttack
+	Practice
+	PuzzleMode
+	PCTraining
+	TSpinDrill
+	Marathon
 )
 
 type ObjectiveSettings interface {
@@ -40,6 +59,216 @@ type ObjectiveSettings interface {
 	CreateFormFields() []FormField
 }
 
+// RankBy decides which of two results of the same objective is better.
+type RankBy int8
+
+const (
+	// Only completed games count, and the fastest one wins
+	RankByTime RankBy = iota
+	// The highest score wins
+	RankByScore
+	// The longest game wins
+	RankBySurvival
+)
+
+// ObjectiveType describes everything the rest of the game needs to know
+// about an objective: how to present it, what its default settings are, and
+// how its settings are stored in a replay.
+type ObjectiveType struct {
+	ID   ObjectiveID
+	Name string
+	Rank RankBy
+	// Games can't be played back, so they aren't saved as replays or entered
+	// into the records
+	NoReplays bool
+	// Games need a starting board, so the objective isn't offered in the
+	// main menu
+	NotInMenu bool
+
+	New    func() ObjectiveSettings
+	Encode func(set ObjectiveSettings, w io.Writer) error
+	Decode func(r io.Reader) (ObjectiveSettings, error)
+}
+
+// ObjectiveTypes lists every playable objective in the order they appear in
+// the main menu. Adding an entry here is enough for a new objective to show
+// up in the menu and to be saved in replays.
+var ObjectiveTypes = []ObjectiveType{
+	BinaryObjectiveType(LineClear, "Sprint", RankByTime, LineClearSettings{
+		Lines: 40,
+	}),
+	BinaryObjectiveType(Marathon, "Marathon", RankByScore, MarathonSettings{
+		Lines: 150,
+	}),
+	BinaryObjectiveType(Endless, "Endless", RankByScore, EndlessSettings{}),
+	BinaryObjectiveType(Survival, "Survival", RankBySurvival, SurvivalSettings{
+		GarbageRate: 1000,
+	}),
+	BinaryObjectiveType(Cheese, "Cheese", RankByTime, CheeseSettings{
+		Garbage: 18,
+	}),
+	BinaryObjectiveType(ScoreAttack, "Score Attack", RankByScore,
+		ScoreAttackSettings{
+			Duration: 120,
+		},
+	),
+	BinaryObjectiveType(Practice, "Practice", RankByScore, PracticeSettings{
+		Gravity: true,
+	}).WithoutReplays(),
+	BinaryObjectiveType(PCTraining, "PC Training", RankByTime,
+		PCTrainingSettings{
+			PerfectClears: 5,
+		},
+	),
+	BinaryObjectiveType(TSpinDrill, "T-Spin Drill", RankByScore,
+		TSpinDrillSettings{
+			Rounds:  20,
+			Doubles: true,
+			Triples: true,
+		},
+	),
+	BinaryObjectiveType(PuzzleMode, "Puzzle", RankByTime, PuzzleSettings{
+		Goal:  ClearLinesGoal,
+		Count: 1,
+		Hold:  true,
+	}).WithoutMenu(),
+}
+
+// MenuObjectiveTypes returns the objectives that can be started on their own.
+func MenuObjectiveTypes() []ObjectiveType {
+	types := make([]ObjectiveType, 0, len(ObjectiveTypes))
+	for _, ot := range ObjectiveTypes {
+		if !ot.NotInMenu {
+			types = append(types, ot)
+		}
+	}
+	return types
+}
+
+func GetObjectiveType(id ObjectiveID) (ObjectiveType, bool) {
+	for _, ot := range ObjectiveTypes {
+		if ot.ID == id {
+			return ot, true
+		}
+	}
+
+	return ObjectiveType{}, false
+}
+
+func GetObjectiveTypeByName(name string) (ObjectiveType, bool) {
+	for _, ot := range ObjectiveTypes {
+		if ot.Name == name {
+			return ot, true
+		}
+	}
+
+	return ObjectiveType{}, false
+}
+
+// BinaryObjectiveType creates an ObjectiveType for settings that are a
+// fixed-size struct, which can be written to a replay as-is.
+func BinaryObjectiveType[T any, PT interface {
+	*T
+	ObjectiveSettings
+}](id ObjectiveID, name string, rank RankBy, defaults T) ObjectiveType {
+	return ObjectiveType{
+		ID:   id,
+		Name: name,
+		Rank: rank,
+		New: func() ObjectiveSettings {
+			set := defaults
+			return PT(&set)
+		},
+		Encode: func(set ObjectiveSettings, w io.Writer) error {
+			typed, ok := set.(PT)
+			if !ok {
+				return fmt.Errorf(
+					"Settings %T do not match objective %v", set, name)
+			}
+			return binary.Write(w, binary.LittleEndian, typed)
+		},
+		Decode: func(r io.Reader) (ObjectiveSettings, error) {
+			var set T
+			err := binary.Read(r, binary.LittleEndian, &set)
+			if err != nil {
+				return nil, err
+			}
+			return PT(&set), nil
+		},
+	}
+}
+
+// WithoutReplays marks an objective whose games can't be played back.
+// Clone copies settings of this objective, so they can be edited without
+// changing the original.
+func (ot ObjectiveType) Clone(set ObjectiveSettings) (ObjectiveSettings, error) {
+	var buf bytes.Buffer
+	err := ot.Encode(set, &buf)
+	if err != nil {
+		return nil, err
+	}
+	return ot.Decode(&buf)
+}
+
+func (ot ObjectiveType) WithoutReplays() ObjectiveType {
+	ot.NoReplays = true
+	return ot
+}
+
+// WithoutMenu marks an objective that is only played through its own scene.
+func (ot ObjectiveType) WithoutMenu() ObjectiveType {
+	ot.NotInMenu = true
+	return ot
+}
+
+// Better reports whether result a beats result b.
+func (ot ObjectiveType) Better(a, b ReplaySummary) bool {
+	switch ot.Rank {
+	case RankByScore:
+		return a.Score > b.Score
+	case RankBySurvival:
+		return a.Frames > b.Frames
+	default:
+		if a.Failed != b.Failed {
+			return !a.Failed
+		}
+		if a.Failed {
+			return a.Lines > b.Lines
+		}
+		return a.Frames < b.Frames
+	}
+}
+
+// Qualifies reports whether a result can count as a personal best at all.
+// Time-ranked objectives have to be completed, and games started from a
+// custom board don't compare with the rest.
+func (ot ObjectiveType) Qualifies(summary ReplaySummary) bool {
+	if summary.CustomBoard {
+		return false
+	}
+	return ot.Rank != RankByTime || !summary.Failed
+}
+
+// ObjectiveCategory names an objective together with its settings, so that
+// results are only ever compared against games played with the same rules.
+func ObjectiveCategory(id ObjectiveID, settings ObjectiveSettings) string {
+	value := reflect.Indirect(reflect.ValueOf(settings))
+	if !value.IsValid() || value.NumField() == 0 {
+		return id.String()
+	}
+	return fmt.Sprintf("%v %+v", id, value.Interface())
+}
+
+var ErrInvalidObjective = errors.New("Invalid objective ID")
+
+func (id ObjectiveID) String() string {
+	ot, ok := GetObjectiveType(id)
+	if !ok {
+		return fmt.Sprintf("ObjectiveID(%d)", int8(id))
+	}
+	return ot.Name
+}
+
 func (gts *GlobalTetrisSettings) CreateFormFields() []FormField {
 	return []FormField{
 		NewIntegerField(
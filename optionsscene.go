@@ -0,0 +1,194 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// OptionsScene edits the settings that aren't tied to a mode. Every change is
+// applied and saved straight away.
+type OptionsScene struct {
+	app *App
+
+	// Scene to go back to when leaving, or nil for the main menu
+	back Scene
+
+	sections []FormSection
+
+	menuFocus    int
+	editingField bool
+}
+
+func (ops *OptionsScene) Init(app *App, back Scene) {
+	ops.app = app
+	ops.back = back
+	settings := app.Settings
+
+	ops.sections = []FormSection{
+		{
+			Name: "Audio",
+			Fields: []FormField{
+				NewIntegerField(
+					"Sound volume",
+					settings.Audio.SoundVolume,
+					func(value int64) {
+						settings.Audio.SoundVolume = value
+						ops.SettingsChanged()
+					},
+					WithMin(0),
+					WithMax(100),
+				),
+				NewIntegerField(
+					"Music volume",
+					settings.Audio.MusicVolume,
+					func(value int64) {
+						settings.Audio.MusicVolume = value
+						ops.SettingsChanged()
+					},
+					WithMin(0),
+					WithMax(100),
+				),
+			},
+		},
+		{
+			Name: "Display",
+			Fields: []FormField{
+				NewBooleanField(
+					"Particles",
+					settings.Display.Particles,
+					func(value bool) {
+						settings.Display.Particles = value
+						ops.SettingsChanged()
+					},
+				),
+				NewBooleanField(
+					"Snap indicators",
+					settings.Display.SnapIndicators,
+					func(value bool) {
+						settings.Display.SnapIndicators = value
+						ops.SettingsChanged()
+					},
+				),
+				NewBooleanField(
+					"Flash finesse faults",
+					settings.Display.FinesseFlash,
+					func(value bool) {
+						settings.Display.FinesseFlash = value
+						ops.SettingsChanged()
+					},
+				),
+			},
+		},
+	}
+}
+
+func (ops *OptionsScene) SettingsChanged() {
+	ops.app.ApplySettings()
+	ops.app.SaveSettings()
+}
+
+func (ops *OptionsScene) numFields() int {
+	count := 0
+	for _, sec := range ops.sections {
+		count += len(sec.Fields)
+	}
+	return count
+}
+
+// Returns the field that has the given index across all sections.
+func (ops *OptionsScene) field(idx int) EditableField {
+	for _, sec := range ops.sections {
+		if idx < len(sec.Fields) {
+			return sec.Fields[idx].Field
+		}
+		idx -= len(sec.Fields)
+	}
+	return nil
+}
+
+func (ops *OptionsScene) HandleEvent(ev tcell.Event) {
+	if ops.editingField {
+		ops.field(ops.menuFocus).HandleInput(ev)
+	}
+}
+
+func (ops *OptionsScene) HandleAction(act Action) {
+	switch act {
+	case MoveUp:
+		ops.editingField = false
+		ops.menuFocus = max(0, ops.menuFocus-1)
+	case MoveDown:
+		ops.editingField = false
+		ops.menuFocus = min(ops.numFields()-1, ops.menuFocus+1)
+	case MenuConfirm:
+		field := ops.field(ops.menuFocus)
+		if field, ok := field.(*BooleanField); ok {
+			field.SetValue(!field.Value)
+		} else {
+			ops.editingField = !ops.editingField
+		}
+	case Quit:
+		if ops.editingField {
+			return
+		}
+		if ops.back != nil {
+			ops.app.NextScene = ops.back
+		} else {
+			ops.app.OpenMenuScene()
+		}
+	}
+}
+
+func (ops *OptionsScene) Update() {
+}
+
+func (ops *OptionsScene) Draw(sw, sh int, rr Area, lag float64) {
+	focusStyle := defStyle.Reverse(true)
+
+	SetString(
+		rr.X,
+		rr.Y,
+		"Options",
+		defStyle)
+
+	position := 2
+	fieldIdx := 0
+	for _, sec := range ops.sections {
+		SetString(
+			rr.X+2,
+			rr.Y+position,
+			sec.Name,
+			defStyle)
+		position += 2
+
+		for _, opt := range sec.Fields {
+			focused := fieldIdx == ops.menuFocus
+			style := defStyle
+			if focused {
+				Screen.SetContent(rr.X, rr.Y+position, '*', nil, defStyle)
+				if !ops.editingField {
+					style = focusStyle
+				}
+			}
+
+			SetString(
+				rr.X+2,
+				rr.Y+position,
+				opt.Name,
+				style,
+			)
+
+			opt.Field.Draw(
+				rr.X+3+runewidth.StringWidth(opt.Name),
+				rr.Y+position,
+				ops.editingField && focused,
+			)
+
+			position += 2
+			fieldIdx++
+		}
+	}
+}
+
+func (ops *OptionsScene) Cleanup() {
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+)
+
+type PCTrainingSettings struct {
+	PerfectClears int64
+}
+
+// PCTrainingObjective is won by making a number of perfect clears. After
+// every placement it checks whether a perfect clear can still be made with
+// the pieces in view, and starts a new attempt on an empty board once it
+// can't.
+type PCTrainingObjective struct {
+	PerfectClears int64
+
+	solver *PCSolver
+	// Piece count when the board was last checked, so swapping with the hold
+	// doesn't check it again
+	checkedAt int64
+
+	cleared  int64
+	missed   int64
+	possible bool
+
+	stats []Stat
+}
+
+func (ps *PCTrainingSettings) Init(es *TetrisField) Objective {
+	po := &PCTrainingObjective{
+		PerfectClears: ps.PerfectClears,
+		solver:        NewPCSolver(),
+		checkedAt:     -1,
+		possible:      true,
+	}
+
+	po.stats = []Stat{
+		CreatePiecesStat(es),
+		CreateElapsedTimeStat(es),
+		po.PCStat(),
+	}
+
+	es.AddLineClearHandler(func(garbage, nonGarbage int) {
+		if !es.BoardIsEmpty() {
+			return
+		}
+		po.cleared++
+		if po.cleared >= po.PerfectClears {
+			es.ObjectiveComplete(fmt.Sprintf("%d perfect clears", po.cleared))
+		}
+	})
+	es.AddSpawnHandler(func() {
+		po.Check(es)
+	})
+
+	return po
+}
+
+func (po *PCTrainingObjective) GetStats() []Stat {
+	return po.stats
+}
+
+// Check works out whether the board can still be perfectly cleared, and
+// clears it for a new attempt if it can't.
+func (po *PCTrainingObjective) Check(es *TetrisField) {
+	if es.gameOver || es.pieceCount == po.checkedAt {
+		return
+	}
+	po.checkedAt = es.pieceCount
+
+	po.possible = po.solver.Reachable(es)
+	if !po.possible {
+		po.missed++
+		es.ClearBoard()
+	}
+}
+
+func (po *PCTrainingObjective) Update(es *TetrisField) {
+	if es.gameOver {
+		return
+	}
+	es.Update()
+}
+
+func (po *PCTrainingObjective) HandleAction(act Action, es *TetrisField) {
+	es.HandleAction(act)
+}
+
+func (po *PCTrainingObjective) PCStat() Stat {
+	return Stat{
+		Compute: func() []string {
+			status := "PC possible"
+			if !po.possible {
+				status = "missed, restarted"
+			}
+
+			return []string{
+				"PERFECT CLEARS",
+				fmt.Sprintf("%d/%d", po.cleared, po.PerfectClears),
+				fmt.Sprintf("%d missed", po.missed),
+				status,
+			}
+		},
+	}
+}
+
+func (ps *PCTrainingSettings) CreateFormFields() []FormField {
+	return []FormField{
+		NewIntegerField(
+			"Perfect clears",
+			ps.PerfectClears,
+			func(value int64) {
+				ps.PerfectClears = value
+			},
+			WithMin(1),
+		),
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// Tallest perfect clear the solver looks for
+const PC_MAX_HEIGHT = 4
+
+// Most positions the solver visits before giving up. A search that gives up
+// counts the perfect clear as still possible.
+const PC_SEARCH_LIMIT = 5000
+
+// Empty rows above the cleared area that pieces start from. Every piece fits
+// in this many rows whatever its rotation.
+const PC_SPAWN_ROWS = 4
+
+// pcBoard holds the bottom rows of the board as bits, with bit
+// row*BOARD_WIDTH+x set for a filled cell. Rows are counted from the bottom.
+type pcBoard uint64
+
+const pcRowMask = pcBoard(1)<<BOARD_WIDTH - 1
+
+// Every cell of the leftmost and rightmost columns, used to stop flood fills
+// from wrapping around a row
+var (
+	pcLeftColumn  = pcColumn(0)
+	pcRightColumn = pcColumn(BOARD_WIDTH - 1)
+)
+
+func pcColumn(x int) pcBoard {
+	var column pcBoard
+	for r := 0; r < PC_MAX_HEIGHT; r++ {
+		column |= 1 << (r*BOARD_WIDTH + x)
+	}
+	return column
+}
+
+// pcRows returns the cells of the bottom h rows.
+func pcRows(h int) pcBoard {
+	return pcBoard(1)<<(h*BOARD_WIDTH) - 1
+}
+
+func (b pcBoard) filled(x, r int) bool {
+	return b&(1<<(r*BOARD_WIDTH+x)) != 0
+}
+
+func (b pcBoard) cells() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// clearLines removes full rows, moving the rows above down, and returns the
+// number removed.
+func (b pcBoard) clearLines() (pcBoard, int) {
+	var cleared pcBoard
+	count := 0
+	for r := 0; r < PC_MAX_HEIGHT; r++ {
+		row := (b >> (r * BOARD_WIDTH)) & pcRowMask
+		if row == pcRowMask {
+			count++
+			continue
+		}
+		cleared |= row << ((r - count) * BOARD_WIDTH)
+	}
+	return cleared, count
+}
+
+// canClear reports whether the board could still be cleared in h rows: every
+// cell has to be within them, and every gap left in them has to be fillable
+// by whole pieces.
+func (b pcBoard) canClear(h int) bool {
+	area := pcRows(h)
+	if b&^area != 0 {
+		return false
+	}
+
+	empty := area &^ b
+	for empty != 0 {
+		region := empty & -empty
+		for {
+			grown := region |
+				(region<<1)&^pcLeftColumn |
+				(region>>1)&^pcRightColumn |
+				region<<BOARD_WIDTH |
+				region>>BOARD_WIDTH
+			grown &= empty
+			if grown == region {
+				break
+			}
+			region = grown
+		}
+		if region.cells()%4 != 0 {
+			return false
+		}
+		empty &^= region
+	}
+	return true
+}
+
+type pcKey struct {
+	board pcBoard
+	h     int8
+	next  int8
+	hold  int8
+}
+
+// PCSolver works out whether a perfect clear can still be made from a board
+// with the pieces the player can see.
+type PCSolver struct {
+	// Cells of each piece in each rotation, relative to the piece's grid
+	cells [][4][]Position
+	// Kicks tried when rotating each piece clockwise and counter-clockwise
+	// from each rotation
+	kicks [][4][2][]Position
+
+	queue  []int
+	failed map[pcKey]bool
+	nodes  int
+}
+
+func NewPCSolver() *PCSolver {
+	s := &PCSolver{
+		cells: make([][4][]Position, len(Pieces)),
+		kicks: make([][4][2][]Position, len(Pieces)),
+	}
+
+	for p, rotations := range Pieces {
+		for rot, grid := range rotations {
+			for y := 0; y < grid.Height; y++ {
+				for x := 0; x < grid.Width; x++ {
+					if grid.MustGet(x, y) {
+						s.cells[p][rot] = append(s.cells[p][rot], Position{X: x, Y: y})
+					}
+				}
+			}
+			s.kicks[p][rot][0] = GetOffsets(p, rot, (rot+1)%4)
+			s.kicks[p][rot][1] = GetOffsets(p, rot, (rot+3)%4)
+		}
+	}
+
+	return s
+}
+
+// Reachable reports whether the field's board can still be perfectly
+// cleared using the piece in play, the next pieces and the held piece.
+// Pieces past the visible queue are unknown, so running out of pieces with
+// a board that could still be cleared counts as reachable.
+func (s *PCSolver) Reachable(es *TetrisField) bool {
+	var board pcBoard
+	for y := 0; y < es.grid.Height; y++ {
+		r := es.grid.Height - 1 - y
+		for x := 0; x < es.grid.Width; x++ {
+			if es.grid.MustGet(x, y) == 0 {
+				continue
+			}
+			if r >= PC_MAX_HEIGHT {
+				return false
+			}
+			board |= 1 << (r*BOARD_WIDTH + x)
+		}
+	}
+
+	queue := append([]int{es.cpIdx}, es.nextPieces...)
+	return s.ReachableFrom(board, queue, es.holdPiece)
+}
+
+// ReachableFrom reports whether a board can be perfectly cleared with the
+// given pieces, the first of which is in play, and the held piece.
+func (s *PCSolver) ReachableFrom(board pcBoard, queue []int, hold int) bool {
+	if board == 0 {
+		return true
+	}
+
+	s.queue = queue
+	s.failed = make(map[pcKey]bool)
+	s.nodes = 0
+
+	for h := 1; h <= PC_MAX_HEIGHT; h++ {
+		if (h*BOARD_WIDTH-board.cells())%4 != 0 || !board.canClear(h) {
+			continue
+		}
+		if s.search(board, h, 0, hold) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *PCSolver) search(board pcBoard, h int, next int, hold int) bool {
+	if board == 0 || next >= len(s.queue) || s.nodes >= PC_SEARCH_LIMIT {
+		return true
+	}
+	s.nodes++
+
+	key := pcKey{board: board, h: int8(h), next: int8(next), hold: int8(hold)}
+	if s.failed[key] {
+		return false
+	}
+
+	curr := s.queue[next]
+	if s.tryPiece(board, h, curr, next+1, hold) {
+		return true
+	}
+	if hold == NO_HOLD_PIECE {
+		// Holding brings out the next piece instead
+		if s.search(board, h, next+1, curr) {
+			return true
+		}
+	} else if hold != curr && s.tryPiece(board, h, hold, next+1, curr) {
+		return true
+	}
+
+	s.failed[key] = true
+	return false
+}
+
+// tryPiece places a piece everywhere it can go and carries on the search from
+// each result that could still be cleared.
+func (s *PCSolver) tryPiece(board pcBoard, h, piece, next, hold int) bool {
+	for _, placed := range s.Placements(board, h, piece) {
+		cleared, lines := (board | placed).clearLines()
+		if !cleared.canClear(h - lines) {
+			continue
+		}
+		if s.search(cleared, h-lines, next, hold) {
+			return true
+		}
+	}
+	return false
+}
+
+type pcState struct {
+	x, y, rot int
+}
+
+// Placements returns the cells a piece could lock into within the bottom h
+// rows of the board, reached by moving, soft dropping and rotating it down
+// from above the stack.
+func (s *PCSolver) Placements(board pcBoard, h int, piece int) []pcBoard {
+	// Rows of the area the piece moves in, counted from the top, with the
+	// board at the bottom
+	height := h + PC_SPAWN_ROWS
+
+	collides := func(st pcState) bool {
+		for _, c := range s.cells[piece][st.rot] {
+			x, y := st.x+c.X, st.y+c.Y
+			r := height - 1 - y
+			if x < 0 || x >= BOARD_WIDTH || r < 0 {
+				return true
+			}
+			if r < PC_MAX_HEIGHT && board.filled(x, r) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Indexed by rotation, x and y, offset so that every position that
+	// doesn't collide fits
+	var seen [4][BOARD_WIDTH + 4][PC_MAX_HEIGHT + 2*PC_SPAWN_ROWS]bool
+	var frontier []pcState
+	visit := func(st pcState) {
+		// Kicks can't take a piece far above where it started
+		if st.y < -PC_SPAWN_ROWS || collides(st) {
+			return
+		}
+		if seen[st.rot][st.x+4][st.y+PC_SPAWN_ROWS] {
+			return
+		}
+		seen[st.rot][st.x+4][st.y+PC_SPAWN_ROWS] = true
+		frontier = append(frontier, st)
+	}
+
+	for rot := 0; rot < 4; rot++ {
+		top := s.cells[piece][rot][0].Y
+		for x := -4; x < BOARD_WIDTH; x++ {
+			visit(pcState{x: x, y: -top, rot: rot})
+		}
+	}
+
+	var placements []pcBoard
+	for len(frontier) > 0 {
+		st := frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+
+		visit(pcState{x: st.x - 1, y: st.y, rot: st.rot})
+		visit(pcState{x: st.x + 1, y: st.y, rot: st.rot})
+		for dir, rot := range []int{(st.rot + 1) % 4, (st.rot + 3) % 4} {
+			for _, kick := range s.kicks[piece][st.rot][dir] {
+				kicked := pcState{x: st.x + kick.X, y: st.y + kick.Y, rot: rot}
+				if !collides(kicked) {
+					visit(kicked)
+					break
+				}
+			}
+		}
+
+		below := pcState{x: st.x, y: st.y + 1, rot: st.rot}
+		if !collides(below) {
+			visit(below)
+			continue
+		}
+
+		var placed pcBoard
+		fits := true
+		for _, c := range s.cells[piece][st.rot] {
+			r := height - 1 - (st.y + c.Y)
+			if r >= h {
+				fits = false
+				break
+			}
+			placed |= 1 << (r*BOARD_WIDTH + st.x + c.X)
+		}
+		if fits && !slices.Contains(placements, placed) {
+			placements = append(placements, placed)
+		}
+	}
+	return placements
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPCSolver(t *testing.T) {
+	// Three rows filled except for a 4x3 area on the right
+	var well pcBoard
+	for r := 0; r < 3; r++ {
+		for x := 0; x < 6; x++ {
+			well |= 1 << (r*BOARD_WIDTH + x)
+		}
+	}
+	// A full bottom row but for one cell, which has a cell above it
+	covered := pcRowMask&^1 | 1<<BOARD_WIDTH
+
+	tests := []struct {
+		name      string
+		board     pcBoard
+		queue     string
+		hold      int
+		reachable bool
+	}{
+		{"Empty board", 0, "SZ", NO_HOLD_PIECE, true},
+		{"I goes in first", well, "ILL", NO_HOLD_PIECE, true},
+		{"I held for later", well, "LIL", NO_HOLD_PIECE, true},
+		{"I comes from hold", well, "LL", 0, true},
+		{"No way to fill", well, "SZO", NO_HOLD_PIECE, false},
+		{"Covered hole", covered, "IJLOSTZ", NO_HOLD_PIECE, false},
+	}
+
+	solver := NewPCSolver()
+	for _, test := range tests {
+		queue, err := ParseQueue(test.queue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reachable := solver.ReachableFrom(test.board, queue, test.hold)
+		if reachable != test.reachable {
+			t.Errorf("%v: expected reachable to be %v, got %v",
+				test.name, test.reachable, reachable)
+		}
+	}
+}
@@ -4,34 +4,59 @@ import (
 	"math/rand"
 )
 
+// Piece given by a generator that has run out
+const NO_NEXT_PIECE = -1
+
 type PieceGenerator interface {
 	NextPiece() int
+	// Clone returns a generator that will produce the same pieces from now on
+	Clone() PieceGenerator
 }
 
 type TrueRandomPieceGenerator struct {
-	rand *rand.Rand
+	rand  *rand.Rand
+	seed  int64
+	draws int
 }
 
 func NewTrueRandomPieceGenerator(seed int64) TrueRandomPieceGenerator {
 	return TrueRandomPieceGenerator{
 		rand: rand.New(rand.NewSource(seed)),
+		seed: seed,
 	}
 }
 
 func (pg *TrueRandomPieceGenerator) NextPiece() int {
+	pg.draws++
 	return pg.rand.Intn(7)
 }
 
+// The state of a rand.Rand can't be copied, so the clone starts from the same
+// seed and draws as many times.
+func (pg *TrueRandomPieceGenerator) Clone() PieceGenerator {
+	clone := NewTrueRandomPieceGenerator(pg.seed)
+	for clone.draws < pg.draws {
+		clone.NextPiece()
+	}
+	return &clone
+}
+
 type BagRandomizer struct {
 	rand *rand.Rand
 	bag  []int
 	curr int
+
+	seed     int64
+	levels   int
+	shuffles int
 }
 
 func NewBagRandomizer(seed int64, levels int) BagRandomizer {
 	br := BagRandomizer{
-		rand: rand.New(rand.NewSource(seed)),
-		bag:  make([]int, 7*levels),
+		rand:   rand.New(rand.NewSource(seed)),
+		bag:    make([]int, 7*levels),
+		seed:   seed,
+		levels: levels,
 	}
 
 	for i := 0; i < 7*levels; i++ {
@@ -44,6 +69,7 @@ func NewBagRandomizer(seed int64, levels int) BagRandomizer {
 }
 
 func (br *BagRandomizer) shuffle() {
+	br.shuffles++
 	for i := len(br.bag) - 1; i > 0; i-- {
 		j := br.rand.Intn(i + 1)
 		tmp := br.bag[i]
@@ -62,3 +88,46 @@ func (br *BagRandomizer) NextPiece() int {
 
 	return p
 }
+
+// Like TrueRandomPieceGenerator, the clone shuffles its bag as many times as
+// this one has.
+func (br *BagRandomizer) Clone() PieceGenerator {
+	clone := NewBagRandomizer(br.seed, br.levels)
+	for clone.shuffles < br.shuffles {
+		clone.shuffle()
+	}
+	clone.curr = br.curr
+	return &clone
+}
+
+// ListPieceGenerator plays a fixed list of pieces, then carries on with
+// another generator.
+type ListPieceGenerator struct {
+	Pieces []int
+	// Generator to carry on with, or nil to run out after the list
+	Then PieceGenerator
+
+	curr int
+}
+
+func (lg *ListPieceGenerator) NextPiece() int {
+	if lg.curr < len(lg.Pieces) {
+		lg.curr++
+		return lg.Pieces[lg.curr-1]
+	}
+	if lg.Then == nil {
+		return NO_NEXT_PIECE
+	}
+	return lg.Then.NextPiece()
+}
+
+func (lg *ListPieceGenerator) Clone() PieceGenerator {
+	clone := &ListPieceGenerator{
+		Pieces: lg.Pieces,
+		curr:   lg.curr,
+	}
+	if lg.Then != nil {
+		clone.Then = lg.Then.Clone()
+	}
+	return clone
+}
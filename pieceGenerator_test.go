@@ -0,0 +1,39 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func drawPieces(pg PieceGenerator, n int) []int {
+	pieces := make([]int, n)
+	for i := range pieces {
+		pieces[i] = pg.NextPiece()
+	}
+	return pieces
+}
+
+func TestPieceGeneratorClone(t *testing.T) {
+	bag := NewBagRandomizer(42, 2)
+	random := NewTrueRandomPieceGenerator(42)
+
+	for _, tc := range []struct {
+		name string
+		pg   PieceGenerator
+	}{
+		{"bag", &bag},
+		{"true random", &random},
+	} {
+		// Clone partway through a bag, so both the shuffles and the position
+		// in the bag have to carry over
+		for _, drawn := range []int{0, 5, 14, 31} {
+			drawPieces(tc.pg, drawn)
+			clone := tc.pg.Clone()
+
+			want := drawPieces(tc.pg, 50)
+			if got := drawPieces(clone, 50); !slices.Equal(got, want) {
+				t.Errorf("%v after %d more: clone drew %v, want %v", tc.name, drawn, got, want)
+			}
+		}
+	}
+}
@@ -263,6 +263,9 @@ func GetOffsets(pieceIdx int, startRot int, endRot int) []Position {
 	return positions
 }
 
+// Index of the T piece in Pieces
+const T_PIECE = 5
+
 var Pieces = [][]Grid[bool]{
 	IPieces,
 	JPieces,
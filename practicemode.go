@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type PracticeSettings struct {
+	Gravity bool
+}
+
+// PracticeObjective never ends. Every placement can be undone and redone, a
+// board can be marked to come back to, and the queue can be chosen by hand.
+type PracticeObjective struct {
+	Gravity bool
+
+	// State when the piece in play came out of the queue
+	current *FieldSnapshot
+	// States before each placement that can be undone, and the states that
+	// undoing left behind
+	undo []FieldSnapshot
+	redo []FieldSnapshot
+	// Board marked to come back to
+	marked *FieldSnapshot
+	// Set when a piece locks, so the next piece to spawn becomes current
+	placed bool
+	// Slot in the queue that the next chosen piece goes into
+	queueSlot int
+
+	stats []Stat
+}
+
+func (ps *PracticeSettings) Init(es *TetrisField) Objective {
+	po := &PracticeObjective{
+		Gravity: ps.Gravity,
+	}
+
+	po.stats = []Stat{
+		CreateLinesStat(es),
+		CreatePiecesStat(es),
+		CreateFinesseStat(es),
+		po.PracticeStat(),
+	}
+
+	// Holding into an empty hold spawns a piece too, but only a placement
+	// makes a step that can be undone
+	es.AddLockHandler(func() {
+		if po.current != nil {
+			po.undo = append(po.undo, *po.current)
+			po.redo = nil
+		}
+		po.placed = true
+	})
+	es.AddSpawnHandler(func() {
+		if po.current == nil || po.placed {
+			po.SetCurrent(es)
+		}
+	})
+
+	return po
+}
+
+func (po *PracticeObjective) GetStats() []Stat {
+	return po.stats
+}
+
+func (po *PracticeObjective) Update(es *TetrisField) {
+	if es.gameOver {
+		return
+	}
+
+	// Keep the gravity timer topped up so the piece never falls by itself
+	if !po.Gravity {
+		es.gravityTimer = BASE_GRAVITY_UNIT + es.fallRate
+	}
+
+	es.Update()
+}
+
+func (po *PracticeObjective) HandleAction(act Action, es *TetrisField) {
+	switch {
+	case act == PracticeUndo:
+		po.Undo(es)
+	case act == PracticeRedo:
+		po.Redo(es)
+	case act == PracticeMark:
+		po.marked = po.current
+	case act == PracticeLoad:
+		po.LoadMarked(es)
+	case act == PracticeReroll:
+		es.ReseedPieces(time.Now().UnixNano())
+		po.queueSlot = 0
+	case act == PracticeGravity:
+		po.Gravity = !po.Gravity
+	case act >= QueueI && act <= QueueZ:
+		es.nextPieces[po.queueSlot] = int(act - QueueI)
+		po.queueSlot = (po.queueSlot + 1) % len(es.nextPieces)
+	default:
+		es.HandleAction(act)
+	}
+}
+
+// SetCurrent remembers the state of the field as the piece in play's
+// starting point.
+func (po *PracticeObjective) SetCurrent(es *TetrisField) {
+	snap := es.Snapshot()
+	po.current = &snap
+	po.placed = false
+	po.queueSlot = 0
+}
+
+// Undo takes back the last placement. If that placement ended the game,
+// there's no new piece in play to redo from, so the game just goes back to
+// before it.
+func (po *PracticeObjective) Undo(es *TetrisField) {
+	if po.current == nil || len(po.undo) == 0 {
+		return
+	}
+
+	if !es.gameOver {
+		po.redo = append(po.redo, *po.current)
+	}
+	es.Restore(po.undo[len(po.undo)-1])
+	po.undo = po.undo[:len(po.undo)-1]
+	po.SetCurrent(es)
+}
+
+// Redo puts back the last placement that was undone.
+func (po *PracticeObjective) Redo(es *TetrisField) {
+	if po.current == nil || len(po.redo) == 0 {
+		return
+	}
+
+	po.undo = append(po.undo, *po.current)
+	es.Restore(po.redo[len(po.redo)-1])
+	po.redo = po.redo[:len(po.redo)-1]
+	po.SetCurrent(es)
+}
+
+// LoadMarked goes back to the marked board. This can be undone like a
+// placement.
+func (po *PracticeObjective) LoadMarked(es *TetrisField) {
+	if po.current == nil || po.marked == nil {
+		return
+	}
+
+	po.undo = append(po.undo, *po.current)
+	po.redo = nil
+	es.Restore(*po.marked)
+	po.SetCurrent(es)
+}
+
+func (po *PracticeObjective) PracticeStat() Stat {
+	return Stat{
+		Compute: func() []string {
+			gravity := "off"
+			if po.Gravity {
+				gravity = "on"
+			}
+			marked := ""
+			if po.marked != nil {
+				marked = " *"
+			}
+
+			return []string{
+				"PRACTICE",
+				fmt.Sprintf("gravity %v", gravity),
+				fmt.Sprintf("undo %d redo %d", len(po.undo), len(po.redo)),
+				"u/y:undo/redo",
+				"m/l:mark/load" + marked,
+				"n:reroll g:gravity",
+				"1-7:next IJLOSTZ",
+			}
+		},
+	}
+}
+
+func (ps *PracticeSettings) CreateFormFields() []FormField {
+	return []FormField{
+		NewBooleanField(
+			"Gravity",
+			ps.Gravity,
+			func(value bool) {
+				ps.Gravity = value
+			},
+		),
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestPracticeUndo(t *testing.T) {
+	es := NewTetrisField(1, DefaultTetrisSettings)
+	po := (&PracticeSettings{}).Init(es).(*PracticeObjective)
+	es.GetRandomPiece()
+
+	// Holding into the empty hold brings out a new piece, but isn't a step
+	// of its own
+	es.HandleAction(SwapHoldPiece)
+	if len(po.undo) != 0 {
+		t.Fatalf("Holding made %d undo steps", len(po.undo))
+	}
+
+	es.HandleAction(HardDrop)
+	es.HandleAction(HardDrop)
+	if len(po.undo) != 2 {
+		t.Fatalf("Two placements made %d undo steps", len(po.undo))
+	}
+
+	po.Undo(es)
+	po.Undo(es)
+	if es.holdPiece != NO_HOLD_PIECE {
+		t.Errorf("Undoing both placements left piece %d held", es.holdPiece)
+	}
+	if len(po.undo) != 0 || len(po.redo) != 2 {
+		t.Errorf("After undoing: undo %d, redo %d", len(po.undo), len(po.redo))
+	}
+
+	po.Redo(es)
+	if len(po.undo) != 1 || es.holdPiece == NO_HOLD_PIECE {
+		t.Errorf("Redo didn't bring back the held piece")
+	}
+}
+
+func TestPracticeActions(t *testing.T) {
+	es := NewTetrisField(1, DefaultTetrisSettings)
+	po := (&PracticeSettings{}).Init(es).(*PracticeObjective)
+	es.GetRandomPiece()
+
+	po.HandleAction(QueueT, es)
+	po.HandleAction(QueueO, es)
+	if es.nextPieces[0] != T_PIECE || es.nextPieces[1] != 3 {
+		t.Errorf("Chose T and O, queue is %v", es.nextPieces)
+	}
+
+	po.HandleAction(PracticeGravity, es)
+	if !po.Gravity {
+		t.Errorf("Gravity wasn't turned on")
+	}
+
+	po.HandleAction(PracticeMark, es)
+	po.HandleAction(HardDrop, es)
+	if es.cpIdx != T_PIECE || len(po.undo) != 1 {
+		t.Fatalf("Playing %d with %d undo steps", es.cpIdx, len(po.undo))
+	}
+
+	po.HandleAction(PracticeUndo, es)
+	po.HandleAction(PracticeRedo, es)
+	if len(po.undo) != 1 || len(po.redo) != 0 {
+		t.Errorf("Undo and redo left undo %d, redo %d", len(po.undo), len(po.redo))
+	}
+
+	po.HandleAction(PracticeLoad, es)
+	if es.pieceCount != 0 || len(po.undo) != 2 {
+		t.Errorf("Loading the mark left %d pieces placed", es.pieceCount)
+	}
+}
@@ -1,10 +1,22 @@
 package main
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/mattn/go-runewidth"
 )
 
+// Longest seed that can be typed in, enough for any int64
+const SEED_MAX_LENGTH = 20
+
+type FormSection struct {
+	Name   string
+	Fields []FormField
+}
+
 type PreGameScene struct {
 	app *App
 
@@ -12,11 +24,19 @@ type PreGameScene struct {
 	tetrisSettings    GlobalTetrisSettings
 	objectiveSettings ObjectiveSettings
 
-	tetrisFormFields    []FormField
-	objectiveFormFields []FormField
+	// Seed typed in by the player, or empty for a random one
+	seed string
+
+	// Replay to race against, if any
+	ghost        *ReplayData
+	useGhostSeed bool
+
+	sections []FormSection
 
 	menuFocus    int
 	editingField bool
+	// Rows the sections are scrolled by to keep the focused field in view
+	scroll int
 }
 
 func (pgs *PreGameScene) Init(
@@ -30,20 +50,131 @@ func (pgs *PreGameScene) Init(
 	pgs.tetrisSettings = tSettings
 	pgs.objectiveSettings = oSettings
 
-	pgs.tetrisFormFields = pgs.tetrisSettings.CreateFormFields()
-	pgs.objectiveFormFields = pgs.objectiveSettings.CreateFormFields()
+	pgs.sections = []FormSection{
+		{
+			Name:   "Tetris Settings",
+			Fields: pgs.tetrisSettings.CreateFormFields(),
+		},
+		{
+			Name:   "Objective Settings",
+			Fields: pgs.objectiveSettings.CreateFormFields(),
+		},
+		{
+			Name: "Game",
+			Fields: []FormField{
+				NewTextField(
+					"Seed (empty for random)",
+					pgs.seed,
+					SEED_MAX_LENGTH,
+					func(value string) {
+						pgs.seed = value
+					},
+				),
+			},
+		},
+	}
 
+	if pgs.ghost != nil {
+		pgs.sections = append(pgs.sections, FormSection{
+			Name: "Ghost",
+			Fields: []FormField{
+				NewBooleanField(
+					"Use ghost's seed",
+					pgs.useGhostSeed,
+					func(value bool) {
+						pgs.useGhostSeed = value
+					},
+				),
+			},
+		})
+	}
 }
 
-func (pgs *PreGameScene) HandleEvent(ev tcell.Event) {
-	if pgs.editingField {
-		idx := pgs.menuFocus - 1
-		if idx < len(pgs.tetrisFormFields) {
-			pgs.tetrisFormFields[idx].Field.HandleInput(ev)
+// InitGhostRace sets up a game that races against the given replay, using
+// the replay's own settings. The form edits a copy of them, since the ghost
+// has to be played back with the settings it was recorded with.
+func (pgs *PreGameScene) InitGhostRace(app *App, ghost ReplayData) {
+	pgs.ghost = &ghost
+
+	settings := ghost.ObjectiveSettings
+	ot, ok := GetObjectiveType(ghost.ObjectiveID)
+	if ok {
+		clone, err := ot.Clone(settings)
+		if err != nil {
+			app.ReportError("Could not copy the ghost's settings", err)
 		} else {
-			idx -= len(pgs.tetrisFormFields)
-			pgs.objectiveFormFields[idx].Field.HandleInput(ev)
+			settings = clone
+		}
+	}
+
+	pgs.Init(
+		app,
+		ghost.ObjectiveID,
+		ghost.TetrisSettings,
+		settings,
+	)
+}
+
+func (pgs *PreGameScene) numFields() int {
+	count := 0
+	for _, sec := range pgs.sections {
+		count += len(sec.Fields)
+	}
+	return count
+}
+
+// Returns the field that has the given index across all sections.
+func (pgs *PreGameScene) field(idx int) EditableField {
+	for _, sec := range pgs.sections {
+		if idx < len(sec.Fields) {
+			return sec.Fields[idx].Field
 		}
+		idx -= len(sec.Fields)
+	}
+	return nil
+}
+
+// Works out the rows of each section header and field below the start
+// button, before scrolling. Sections without fields are left out.
+func (pgs *PreGameScene) layout() (headers, fields []int) {
+	position := 2
+	for _, sec := range pgs.sections {
+		if len(sec.Fields) == 0 {
+			continue
+		}
+		headers = append(headers, position)
+		position += 2
+		for range sec.Fields {
+			fields = append(fields, position)
+			position += 2
+		}
+	}
+	return headers, fields
+}
+
+// Scrolls the sections so the focused field fits in the given height, with
+// the start button always shown above them.
+func (pgs *PreGameScene) scrollToFocus(height int) {
+	if pgs.menuFocus == 0 {
+		pgs.scroll = 0
+		return
+	}
+
+	_, fields := pgs.layout()
+	row := fields[pgs.menuFocus-1]
+	if row-pgs.scroll >= height {
+		pgs.scroll = row - height + 1
+	}
+	// Going back up, show the row above too, which may be the header
+	if row-pgs.scroll < 4 {
+		pgs.scroll = row - 4
+	}
+	pgs.scroll = max(0, pgs.scroll)
+}
+
+func (pgs *PreGameScene) HandleEvent(ev tcell.Event) {
+	if pgs.editingField {
+		pgs.field(pgs.menuFocus - 1).HandleInput(ev)
 	}
 }
 
@@ -58,26 +189,15 @@ func (pgs *PreGameScene) HandleAction(act Action) {
 	case MoveDown:
 		pgs.editingField = false
 		pgs.menuFocus = min(
-			len(pgs.tetrisFormFields)+len(pgs.objectiveFormFields),
+			pgs.numFields(),
 			pgs.menuFocus+1,
 		)
 	case MenuConfirm:
 		if pgs.menuFocus == 0 {
-			pgs.app.OpenGameScene(
-				pgs.tetrisSettings,
-				pgs.objectiveID,
-				pgs.objectiveSettings,
-			)
+			pgs.StartGame()
 		} else {
 			// If the current field is a boolean field, toggle its value
-			var field EditableField
-			idx := pgs.menuFocus - 1
-			if idx < len(pgs.tetrisFormFields) {
-				field = pgs.tetrisFormFields[idx].Field
-			} else {
-				idx -= len(pgs.tetrisFormFields)
-				field = pgs.objectiveFormFields[idx].Field
-			}
+			field := pgs.field(pgs.menuFocus - 1)
 
 			if field, ok := field.(*BooleanField); ok {
 				field.SetValue(!field.Value)
@@ -90,83 +210,126 @@ func (pgs *PreGameScene) HandleAction(act Action) {
 	}
 }
 
-func (pgs *PreGameScene) Update() {
-}
+func (pgs *PreGameScene) StartGame() {
+	if sv, ok := pgs.objectiveSettings.(ObjectiveSettingsValidator); ok {
+		err := sv.Validate(pgs.tetrisSettings)
+		if err != nil {
+			pgs.app.ShowToast(err.Error())
+			return
+		}
+	}
 
-func (pgs *PreGameScene) Draw(sw, sh int, rr Area, lag float64) {
-	yPosition := pgs.menuFocus * 2
-	if pgs.menuFocus > 0 {
-		yPosition += 2
+	options := make([]GameSceneOption, 0)
+	if seed := strings.TrimSpace(pgs.seed); seed != "" {
+		value, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			pgs.app.ShowToast(fmt.Sprintf("Invalid seed %q", seed))
+			return
+		}
+		options = append(options, WithSeed(value))
 	}
-	if pgs.menuFocus > len(pgs.tetrisFormFields) {
-		yPosition += 2
+	if pgs.ghost != nil {
+		options = append(options, WithGhost(*pgs.ghost))
+		if pgs.ghost.Board != nil {
+			options = append(options, WithBoard(pgs.ghost.Board))
+		}
+		// Overrides a seed typed in
+		if pgs.useGhostSeed {
+			options = append(options, WithSeed(pgs.ghost.Seed))
+		}
+	} else {
+		// Races use the ghost's settings, which shouldn't replace the
+		// player's own
+		err := pgs.app.Settings.SetGameSettings(
+			pgs.objectiveID,
+			pgs.tetrisSettings,
+			pgs.objectiveSettings,
+		)
+		if err != nil {
+			pgs.app.ReportError("Could not save settings", err)
+		} else {
+			pgs.app.SaveSettings()
+		}
 	}
 
-	focusStyle := defStyle.Reverse(true)
+	pgs.app.OpenGameScene(
+		pgs.tetrisSettings,
+		pgs.objectiveID,
+		pgs.objectiveSettings,
+		options...,
+	)
+}
 
-	// Draw focus marker
-	Screen.SetContent(
-		rr.X,
-		rr.Y+yPosition,
-		'*',
-		nil, defStyle)
+func (pgs *PreGameScene) Update() {
+}
+
+func (pgs *PreGameScene) Draw(sw, sh int, rr Area, lag float64) {
+	focusStyle := defStyle.Reverse(true)
 
 	// Draw confirm button
-	if yPosition == 0 {
-		SetString(
-			rr.X+2,
-			rr.Y+0,
-			"Start Game",
-			focusStyle)
-	} else {
-		SetString(
-			rr.X+2,
-			rr.Y+0,
-			"Start Game",
-			defStyle)
+	style := defStyle
+	if pgs.menuFocus == 0 {
+		style = focusStyle
+		Screen.SetContent(rr.X, rr.Y, '*', nil, defStyle)
 	}
-
-	// Draw tetris settings
 	SetString(
 		rr.X+2,
-		rr.Y+2,
-		"Tetris Settings",
-		defStyle)
-
-	for i, opt := range pgs.tetrisFormFields {
-		position := 4 + 2*i
-		style := defStyle
-		if position == yPosition && !pgs.editingField {
-			style = focusStyle
-		}
-		SetString(
-			rr.X+2,
-			rr.Y+position,
-			opt.Name,
-			style,
-		)
+		rr.Y+0,
+		"Start Game",
+		style)
 
-		opt.Field.Draw(
-			rr.X+3+runewidth.StringWidth(opt.Name),
-			rr.Y+position,
-			pgs.editingField && position == yPosition,
+	// Draw objective name
+	if ot, ok := GetObjectiveType(pgs.objectiveID); ok {
+		SetStringArray(
+			rr.Right()-1,
+			rr.Y,
+			defStyle,
+			true,
+			ot.Name,
 		)
 	}
 
-	// Draw objective settings
-	if len(pgs.objectiveFormFields) > 0 {
-		SetString(
-			rr.X+2,
-			rr.Y+2*(2+len(pgs.tetrisFormFields)),
-			"Objective Settings",
-			defStyle)
+	// Draw each section, skipping the ones without any fields and the rows
+	// scrolled out of view
+	pgs.scrollToFocus(rr.Height)
+	headers, fields := pgs.layout()
+	visible := func(row int) bool {
+		return row-pgs.scroll >= 2 && row-pgs.scroll < rr.Height
+	}
+
+	secIdx := 0
+	fieldIdx := 1
+	for _, sec := range pgs.sections {
+		if len(sec.Fields) == 0 {
+			continue
+		}
+
+		if row := headers[secIdx]; visible(row) {
+			SetString(
+				rr.X+2,
+				rr.Y+row-pgs.scroll,
+				sec.Name,
+				defStyle)
+		}
+		secIdx++
+
+		for _, opt := range sec.Fields {
+			row := fields[fieldIdx-1]
+			if !visible(row) {
+				fieldIdx++
+				continue
+			}
+			position := row - pgs.scroll
 
-		for i, opt := range pgs.objectiveFormFields {
-			position := 4 + 2*(1+len(pgs.tetrisFormFields)+i)
+			focused := fieldIdx == pgs.menuFocus
 			style := defStyle
-			if position == yPosition {
-				style = focusStyle
+			if focused {
+				Screen.SetContent(rr.X, rr.Y+position, '*', nil, defStyle)
+				if !pgs.editingField {
+					style = focusStyle
+				}
 			}
+
 			SetString(
 				rr.X+2,
 				rr.Y+position,
@@ -177,10 +340,20 @@ func (pgs *PreGameScene) Draw(sw, sh int, rr Area, lag float64) {
 			opt.Field.Draw(
 				rr.X+3+runewidth.StringWidth(opt.Name),
 				rr.Y+position,
-				pgs.editingField && position == yPosition,
+				pgs.editingField && focused,
 			)
+
+			fieldIdx++
 		}
 	}
+
+	// Scroll indicators
+	if pgs.scroll > 0 {
+		Screen.SetContent(rr.Right()-1, rr.Y+2, '^', nil, defStyle)
+	}
+	if len(fields) > 0 && fields[len(fields)-1]-pgs.scroll >= rr.Height {
+		Screen.SetContent(rr.Right()-1, rr.Bottom()-1, 'v', nil, defStyle)
+	}
 }
 
 func (pgs *PreGameScene) Cleanup() {
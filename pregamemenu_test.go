@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestGhostRaceSettingsCopy(t *testing.T) {
+	ghost := ReplayData{
+		Seed:              1,
+		TetrisSettings:    DefaultTetrisSettings,
+		ObjectiveID:       LineClear,
+		ObjectiveSettings: &LineClearSettings{Lines: 40},
+	}
+
+	pgs := &PreGameScene{}
+	pgs.InitGhostRace(nil, ghost)
+	lines := pgs.sections[1].Fields[0].Field.(*IntegerField)
+	lines.OnChange(20)
+
+	if got := pgs.objectiveSettings.(*LineClearSettings).Lines; got != 20 {
+		t.Errorf("Form set %d lines, want 20", got)
+	}
+	if got := pgs.ghost.ObjectiveSettings.(*LineClearSettings).Lines; got != 40 {
+		t.Errorf("Editing the form changed the ghost to %d lines", got)
+	}
+}
+
+func TestPreGameSceneScrolls(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	screen.SetSize(MIN_WIDTH, MIN_HEIGHT)
+	Screen = screen
+	rr := Area{Width: MIN_WIDTH, Height: MIN_HEIGHT}
+
+	for _, ot := range ObjectiveTypes {
+		// A race has the most fields
+		pgs := &PreGameScene{}
+		pgs.InitGhostRace(nil, ReplayData{
+			TetrisSettings:    DefaultTetrisSettings,
+			ObjectiveID:       ot.ID,
+			ObjectiveSettings: ot.New(),
+		})
+
+		for focus := 0; focus <= pgs.numFields(); focus++ {
+			pgs.menuFocus = focus
+			screen.Clear()
+			pgs.Draw(MIN_WIDTH, MIN_HEIGHT, rr, 0)
+
+			shown := false
+			for y := 0; y < rr.Height; y++ {
+				r, _, _, _ := screen.GetContent(0, y)
+				shown = shown || r == '*'
+			}
+			if !shown {
+				t.Errorf("%v: field %d is out of view", ot.Name, focus)
+			}
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Puzzles are packaged with the game, so they're read relative to it like the
+// other assets.
+const PUZZLE_DIR = "puzzles"
+
+const PUZZLE_FILE_EXTENSION = ".txt"
+
+// Stores which puzzles have been solved alongside the replays. Hidden so it
+// isn't listed as a replay itself.
+const PUZZLE_PROGRESS_FILE = ".puzzles.json"
+
+// Puzzle is a board to play from with a goal to reach.
+type Puzzle struct {
+	// File name without the extension, which identifies the puzzle
+	Name     string
+	Title    string
+	Board    *Board
+	Settings PuzzleSettings
+
+	// Set if the file couldn't be read
+	Err error
+}
+
+// UnmarshalText reads a puzzle file. It's a board file, as read by
+// Board.UnmarshalText, with a few more fields:
+//
+//	title: Name shown in the list
+//	goal: lines N | pc | tsd [N] | survive N
+//	allow hold: yes | no
+//
+// The board's queue holds every piece the puzzle gives, so it can't be empty.
+func (p *Puzzle) UnmarshalText(text []byte) error {
+	p.Settings = PuzzleSettings{
+		Goal:  ClearLinesGoal,
+		Count: 1,
+		Hold:  true,
+	}
+
+	var boardText strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(string(text)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		key, value, isField := strings.Cut(strings.TrimSpace(line), ":")
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch {
+		case isField && key == "title":
+			p.Title = value
+		case isField && key == "goal":
+			err = p.Settings.parseGoal(value)
+		case isField && key == "allow hold":
+			switch value {
+			case "yes":
+				p.Settings.Hold = true
+			case "no":
+				p.Settings.Hold = false
+			default:
+				err = fmt.Errorf("Invalid hold setting %q", value)
+			}
+		default:
+			boardText.WriteString(line)
+			boardText.WriteByte('\n')
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	p.Board = &Board{}
+	err := p.Board.UnmarshalText([]byte(boardText.String()))
+	if err != nil {
+		return err
+	}
+	if len(p.Board.Queue) == 0 {
+		return errors.New("Puzzle has no queue")
+	}
+
+	p.Settings.Pieces = int64(len(p.Board.Queue))
+	if p.Board.Hold != NO_HOLD_PIECE && p.Settings.Hold {
+		p.Settings.Pieces++
+	}
+	if p.Settings.Goal == SurviveGoal && p.Settings.Count > p.Settings.Pieces {
+		return fmt.Errorf(
+			"Puzzle asks for %v pieces but only gives %v",
+			p.Settings.Count, p.Settings.Pieces)
+	}
+	return nil
+}
+
+func (ps *PuzzleSettings) parseGoal(text string) error {
+	name, count, hasCount := strings.Cut(text, " ")
+
+	switch name {
+	case "lines":
+		ps.Goal = ClearLinesGoal
+	case "pc":
+		ps.Goal = PerfectClearGoal
+	case "tsd":
+		ps.Goal = TSpinDoubleGoal
+	case "survive":
+		ps.Goal = SurviveGoal
+	default:
+		return fmt.Errorf("Unknown goal %q", name)
+	}
+
+	ps.Count = 1
+	if hasCount && ps.Goal != PerfectClearGoal {
+		n, err := strconv.ParseInt(strings.TrimSpace(count), 10, 64)
+		if err != nil || n < 1 {
+			return fmt.Errorf("Invalid goal count %q", count)
+		}
+		ps.Count = n
+	} else if ps.Goal == ClearLinesGoal || ps.Goal == SurviveGoal {
+		return fmt.Errorf("Goal %q needs a count", name)
+	}
+	return nil
+}
+
+// LoadPuzzles reads every puzzle in the puzzle directory, sorted by name.
+// Puzzles that couldn't be read are included with their error.
+func LoadPuzzles() ([]*Puzzle, error) {
+	entries, err := os.ReadDir(PUZZLE_DIR)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var puzzles []*Puzzle
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), PUZZLE_FILE_EXTENSION)
+		if !ok || e.IsDir() {
+			continue
+		}
+
+		p := &Puzzle{Name: name, Title: name}
+		data, err := os.ReadFile(filepath.Join(PUZZLE_DIR, e.Name()))
+		if err == nil {
+			err = p.UnmarshalText(data)
+		}
+		p.Err = err
+		puzzles = append(puzzles, p)
+	}
+	return puzzles, nil
+}
+
+// PuzzleProgress remembers when each puzzle was first solved.
+type PuzzleProgress struct {
+	Solved map[string]time.Time
+}
+
+func LoadPuzzleProgress() (*PuzzleProgress, error) {
+	pp := &PuzzleProgress{
+		Solved: make(map[string]time.Time),
+	}
+
+	data, err := os.ReadFile(replayPath(PUZZLE_PROGRESS_FILE))
+	if errors.Is(err, fs.ErrNotExist) {
+		return pp, nil
+	}
+	if err != nil {
+		return pp, err
+	}
+
+	err = json.Unmarshal(data, pp)
+	if pp.Solved == nil {
+		pp.Solved = make(map[string]time.Time)
+	}
+	return pp, err
+}
+
+func (pp *PuzzleProgress) Save() error {
+	data, err := json.MarshalIndent(pp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(REPLAY_DIR, 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(replayPath(PUZZLE_PROGRESS_FILE), data, 0644)
+}
+
+// MarkSolved records a puzzle as solved, keeping the date it was first
+// solved. It returns whether the puzzle wasn't solved before.
+func (pp *PuzzleProgress) MarkSolved(name string) bool {
+	if _, ok := pp.Solved[name]; ok {
+		return false
+	}
+	pp.Solved[name] = time.Now()
+	return true
+}
+
+func (pp *PuzzleProgress) IsSolved(name string) bool {
+	_, ok := pp.Solved[name]
+	return ok
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPuzzleUnmarshalText(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want PuzzleSettings
+		ok   bool
+	}{
+		{"queue: IO", PuzzleSettings{ClearLinesGoal, 1, 2, true}, true},
+		{"goal: lines 2\nqueue: IOT", PuzzleSettings{ClearLinesGoal, 2, 3, true}, true},
+		{"goal: pc\nallow hold: no\nqueue: I", PuzzleSettings{PerfectClearGoal, 1, 1, false}, true},
+		{"goal: tsd 2\nqueue: TT", PuzzleSettings{TSpinDoubleGoal, 2, 2, true}, true},
+		// The held piece counts when it can be swapped in
+		{"goal: survive 3\nhold: I\nqueue: OT", PuzzleSettings{SurviveGoal, 3, 3, true}, true},
+		{"goal: survive 3\nhold: I\nallow hold: no\nqueue: OT", PuzzleSettings{}, false},
+		{"goal: survive 4\nqueue: IOT", PuzzleSettings{}, false},
+		{"goal: lines 1", PuzzleSettings{}, false},
+		{"allow hold: maybe\nqueue: I", PuzzleSettings{}, false},
+		{"author: me\nqueue: I", PuzzleSettings{}, false},
+		{"goal: lines 1\nqueue: I\n..........\nGGGGGGGGG", PuzzleSettings{}, false},
+	} {
+		var p Puzzle
+		err := p.UnmarshalText([]byte(tc.text))
+		if !tc.ok {
+			if err == nil {
+				t.Errorf("%q: expected an error", tc.text)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tc.text, err)
+		} else if p.Settings != tc.want {
+			t.Errorf("%q: settings %+v, want %+v", tc.text, p.Settings, tc.want)
+		}
+	}
+}
+
+func TestPuzzleParseGoal(t *testing.T) {
+	for _, tc := range []struct {
+		text  string
+		goal  PuzzleGoal
+		count int64
+		ok    bool
+	}{
+		{"lines 4", ClearLinesGoal, 4, true},
+		{"pc", PerfectClearGoal, 1, true},
+		// A perfect clear is a perfect clear, however many are asked for
+		{"pc 3", PerfectClearGoal, 1, true},
+		{"tsd", TSpinDoubleGoal, 1, true},
+		{"tsd 2", TSpinDoubleGoal, 2, true},
+		{"survive 10", SurviveGoal, 10, true},
+		{"lines", 0, 0, false},
+		{"survive", 0, 0, false},
+		{"lines 0", 0, 0, false},
+		{"lines -2", 0, 0, false},
+		{"tsd two", 0, 0, false},
+		{"tetris 1", 0, 0, false},
+	} {
+		var ps PuzzleSettings
+		err := ps.parseGoal(tc.text)
+		if !tc.ok {
+			if err == nil {
+				t.Errorf("%q: expected an error", tc.text)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tc.text, err)
+		} else if ps.Goal != tc.goal || ps.Count != tc.count {
+			t.Errorf("%q: goal %v %v, want %v %v", tc.text, ps.Goal, ps.Count, tc.goal, tc.count)
+		}
+	}
+}
+
+// Starts a puzzle whose goal can't be reached, so it only ends once the
+// pieces run out.
+func startPuzzle(t *testing.T, text string) *TetrisField {
+	var p Puzzle
+	err := p.UnmarshalText([]byte("goal: lines 4\n" + text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	es := NewTetrisField(1, DefaultTetrisSettings)
+	es.LoadBoard(p.Board)
+	p.Settings.Init(es)
+	es.GetRandomPiece()
+	return es
+}
+
+func TestPuzzleQueueRunsOut(t *testing.T) {
+	es := startPuzzle(t, "queue: IO")
+	if es.cpIdx != 0 || !slices.Equal(es.nextPieces[:2], []int{3, NO_NEXT_PIECE}) {
+		t.Fatalf("Playing %d with %v next", es.cpIdx, es.nextPieces)
+	}
+
+	es.HandleAction(HardDrop)
+	// The last piece can't be held, since nothing would replace it
+	es.HandleAction(SwapHoldPiece)
+	if es.cpIdx != 3 || es.holdPiece != NO_HOLD_PIECE {
+		t.Fatalf("Held the last piece: playing %d, holding %d", es.cpIdx, es.holdPiece)
+	}
+
+	es.HandleAction(HardDrop)
+	if !es.gameOver || !es.failed || es.pieceCount != 2 {
+		t.Errorf("Game not lost after its %d pieces", es.pieceCount)
+	}
+}
+
+func TestPuzzleHeldPieceComesLast(t *testing.T) {
+	es := startPuzzle(t, "hold: T\nqueue: I")
+
+	es.HandleAction(HardDrop)
+	if es.gameOver || es.cpIdx != 5 || es.holdPiece != NO_HOLD_PIECE {
+		t.Fatalf("Playing %d and holding %d after the queue", es.cpIdx, es.holdPiece)
+	}
+
+	es.HandleAction(HardDrop)
+	if !es.gameOver || es.pieceCount != 2 {
+		t.Errorf("Game not over after its %d pieces", es.pieceCount)
+	}
+}
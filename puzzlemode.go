@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+)
+
+type PuzzleGoal int8
+
+const (
+	ClearLinesGoal PuzzleGoal = iota
+	PerfectClearGoal
+	TSpinDoubleGoal
+	SurviveGoal
+)
+
+// PuzzleSettings describe what a puzzle asks for. The board and the pieces
+// it's played with are stored in the replay as its starting board.
+type PuzzleSettings struct {
+	Goal PuzzleGoal
+	// Lines to clear, T-spin doubles to make or pieces to place, depending on
+	// the goal
+	Count int64
+	// Number of pieces the puzzle gives, including one that starts in hold
+	Pieces int64
+	Hold   bool
+}
+
+// PuzzleObjective is won by reaching the goal before the pieces run out.
+type PuzzleObjective struct {
+	PuzzleSettings
+
+	tSpinDoubles int64
+
+	stats []Stat
+}
+
+func (ps *PuzzleSettings) Init(es *TetrisField) Objective {
+	po := &PuzzleObjective{
+		PuzzleSettings: *ps,
+	}
+
+	po.stats = []Stat{
+		CreatePiecesStat(es),
+		po.PuzzleStat(es),
+	}
+
+	// The board's queue is every piece the puzzle gives, so nothing comes
+	// after it
+	if lg, ok := es.pieceGenerator.(*ListPieceGenerator); ok {
+		lg.Then = nil
+		lg.curr = 0
+		es.FillNextPieces()
+	}
+
+	// Runs after every lock, once its lines have cleared, so the goal is
+	// checked before the next piece would spawn
+	es.AddLineClearHandler(func(garbage, nonGarbage int) {
+		if es.lastLockTSpin == FullSpin && garbage+nonGarbage == 2 {
+			po.tSpinDoubles++
+		}
+		po.CheckGoal(es)
+	})
+
+	return po
+}
+
+func (po *PuzzleObjective) GetStats() []Stat {
+	return po.stats
+}
+
+// Progress returns how far along the goal the game is, and how far it has to
+// go.
+func (po *PuzzleObjective) Progress(es *TetrisField) (int64, int64) {
+	switch po.Goal {
+	case ClearLinesGoal:
+		return es.lines, po.Count
+	case TSpinDoubleGoal:
+		return po.tSpinDoubles, po.Count
+	case SurviveGoal:
+		return es.pieceCount, po.Count
+	}
+
+	if es.pieceCount > 0 && es.BoardIsEmpty() {
+		return 1, 1
+	}
+	return 0, 1
+}
+
+// CheckGoal ends the game once the goal is reached, or once the pieces have
+// run out without reaching it.
+func (po *PuzzleObjective) CheckGoal(es *TetrisField) {
+	if es.gameOver {
+		return
+	}
+
+	if done, goal := po.Progress(es); done >= goal {
+		es.ObjectiveComplete("Solved")
+	} else if es.pieceCount >= po.Pieces {
+		es.ObjectiveFailed("Out of pieces")
+	}
+}
+
+func (po *PuzzleObjective) Update(es *TetrisField) {
+	if es.gameOver {
+		return
+	}
+	es.Update()
+}
+
+func (po *PuzzleObjective) HandleAction(act Action, es *TetrisField) {
+	if act == SwapHoldPiece && !po.Hold {
+		return
+	}
+	es.HandleAction(act)
+}
+
+func (po *PuzzleObjective) PuzzleStat(es *TetrisField) Stat {
+	return Stat{
+		Compute: func() []string {
+			done, goal := po.Progress(es)
+			hold := "hold on"
+			if !po.Hold {
+				hold = "hold off"
+			}
+
+			return []string{
+				"GOAL",
+				po.Describe(),
+				fmt.Sprintf("%d/%d", min(done, goal), goal),
+				fmt.Sprintf("%d pieces left", max(0, po.Pieces-es.pieceCount)),
+				hold,
+			}
+		},
+	}
+}
+
+// Describe names the goal, such as "Clear 4 lines".
+func (ps *PuzzleSettings) Describe() string {
+	switch ps.Goal {
+	case ClearLinesGoal:
+		return fmt.Sprintf("Clear %d lines", ps.Count)
+	case PerfectClearGoal:
+		return "Perfect clear"
+	case TSpinDoubleGoal:
+		if ps.Count == 1 {
+			return "T-spin double"
+		}
+		return fmt.Sprintf("%d T-spin doubles", ps.Count)
+	case SurviveGoal:
+		return fmt.Sprintf("Place %d pieces", ps.Count)
+	}
+	return "Unknown goal"
+}
+
+// Puzzles are chosen from their own list rather than set up in the pre-game
+// menu, so there is nothing to change here.
+func (ps *PuzzleSettings) CreateFormFields() []FormField {
+	return nil
+}
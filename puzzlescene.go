@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Width of the column listing puzzle titles
+const PUZZLE_TITLE_WIDTH = 28
+
+// PuzzleScene lists the packaged puzzles and which of them have been solved.
+type PuzzleScene struct {
+	app *App
+
+	puzzles  []*Puzzle
+	progress *PuzzleProgress
+	focus    int
+	// Set when the scene is left to play a puzzle, so the progress is read
+	// again once the player comes back
+	stale bool
+}
+
+func (ps *PuzzleScene) Init(app *App) {
+	ps.app = app
+
+	puzzles, err := LoadPuzzles()
+	if err != nil {
+		app.ReportError("Could not load puzzles", err)
+	}
+	ps.puzzles = puzzles
+
+	progress, err := LoadPuzzleProgress()
+	if err != nil {
+		app.ReportError("Could not load puzzle progress", err)
+	}
+	ps.progress = progress
+}
+
+func (ps *PuzzleScene) HandleEvent(ev tcell.Event) {
+}
+
+func (ps *PuzzleScene) HandleAction(act Action) {
+	switch act {
+	case MoveUp:
+		ps.focus = max(0, ps.focus-1)
+	case MoveDown:
+		ps.focus = min(len(ps.puzzles)-1, ps.focus+1)
+	case MenuConfirm:
+		if len(ps.puzzles) > 0 {
+			ps.Play(ps.puzzles[ps.focus])
+		}
+	case Quit:
+		ps.app.OpenMenuScene()
+	}
+}
+
+func (ps *PuzzleScene) Play(p *Puzzle) {
+	if p.Err != nil {
+		ps.app.ReportError("Could not load puzzle", p.Err)
+		return
+	}
+
+	settings := p.Settings
+	ps.app.OpenGameScene(
+		ps.app.Settings.TetrisSettingsFor(PuzzleMode),
+		PuzzleMode,
+		&settings,
+		WithPuzzle(p),
+		WithBack(ps),
+	)
+}
+
+func (ps *PuzzleScene) Update() {
+	if !ps.stale {
+		return
+	}
+	ps.stale = false
+
+	progress, err := LoadPuzzleProgress()
+	if err != nil {
+		ps.app.ReportError("Could not load puzzle progress", err)
+	}
+	ps.progress = progress
+}
+
+func (ps *PuzzleScene) Draw(sw, sh int, rr Area, lag float64) {
+	SetString(rr.X, rr.Y, "Puzzles", defStyle)
+
+	if len(ps.puzzles) == 0 {
+		SetString(
+			rr.X,
+			rr.Y+2,
+			fmt.Sprintf("No puzzles found in %v/", PUZZLE_DIR),
+			defStyle.Dim(true),
+		)
+		return
+	}
+
+	solved := 0
+	for _, p := range ps.puzzles {
+		if ps.progress.IsSolved(p.Name) {
+			solved++
+		}
+	}
+	SetString(
+		rr.X,
+		rr.Y+1,
+		fmt.Sprintf("%d/%d solved  enter:play q:back", solved, len(ps.puzzles)),
+		defStyle.Dim(true),
+	)
+
+	header := defStyle.Underline(true)
+	SetString(rr.X+4, rr.Y+3, "Puzzle", header)
+	SetString(rr.X+4+PUZZLE_TITLE_WIDTH, rr.Y+3, "Goal", header)
+	for i, p := range ps.puzzles {
+		y := rr.Y + 4 + i
+		style := defStyle
+		if i == ps.focus {
+			style = style.Reverse(true)
+			Screen.SetContent(rr.X, y, '*', nil, defStyle)
+		}
+
+		if ps.progress.IsSolved(p.Name) {
+			Screen.SetContent(
+				rr.X+2, y, '✓', nil, defStyle.Foreground(tcell.ColorGreen))
+		}
+
+		goal := p.Settings.Describe()
+		if p.Err != nil {
+			style = style.Dim(true)
+			goal = p.Err.Error()
+		}
+		SetString(
+			rr.X+4,
+			y,
+			fmt.Sprintf("%-*v%v", PUZZLE_TITLE_WIDTH, p.Title, goal),
+			style,
+		)
+	}
+}
+
+func (ps *PuzzleScene) Cleanup() {
+	ps.stale = true
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"time"
+)
+
+// Stores the best results of every objective and settings combination
+// alongside the replays. Hidden so it isn't listed as a replay itself.
+const RECORDS_FILE = ".records.json"
+
+// Number of results kept for each objective and settings combination
+const RECORDS_PER_CATEGORY = 10
+
+// Record is one of the best results in its category.
+type Record struct {
+	Summary ReplaySummary
+	Date    time.Time
+	// Name of the replay file, or empty if the replay wasn't saved
+	Replay string
+}
+
+// RecordBook holds the top results of every category, best first.
+type RecordBook struct {
+	Categories map[string][]Record
+	// Set when the records were started without looking at the replays that
+	// were already saved, so they still have to be rebuilt from them
+	Partial bool `json:",omitempty"`
+}
+
+// LoadRecordBook reads the records file. If there isn't one yet, the records
+// start out empty and partial. Working them out from the saved replays means
+// simulating every one, so that is left to Rebuild, away from the UI.
+func LoadRecordBook() (*RecordBook, error) {
+	rb := &RecordBook{
+		Categories: make(map[string][]Record),
+	}
+
+	data, err := os.ReadFile(replayPath(RECORDS_FILE))
+	if errors.Is(err, fs.ErrNotExist) {
+		rb.Partial = true
+		return rb, nil
+	}
+	if err != nil {
+		return rb, err
+	}
+
+	err = json.Unmarshal(data, rb)
+	if rb.Categories == nil {
+		rb.Categories = make(map[string][]Record)
+	}
+	return rb, err
+}
+
+// Rebuild replaces the records with the best results among the saved
+// replays, decoding and simulating each of them. Nothing is written if there
+// are no replays.
+func (rb *RecordBook) Rebuild() error {
+	entries, err := ListReplays()
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	for _, entry := range entries {
+		entry.Decode()
+	}
+	return rb.RebuildFrom(entries)
+}
+
+// RebuildFrom replaces the records with the best results among replay
+// entries that have already been decoded, and saves them.
+func (rb *RecordBook) RebuildFrom(entries []*ReplayEntry) error {
+	rb.Categories = make(map[string][]Record)
+	for _, entry := range entries {
+		if !entry.Decoded || entry.Err != nil {
+			continue
+		}
+		rb.Add(Record{
+			Summary: entry.Summary,
+			Date:    entry.ModTime,
+			Replay:  entry.Name,
+		})
+	}
+	rb.Partial = false
+	return rb.Save()
+}
+
+func (rb *RecordBook) Save() error {
+	data, err := json.MarshalIndent(rb, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(REPLAY_DIR, 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(replayPath(RECORDS_FILE), data, 0644)
+}
+
+// Add inserts a result into its category and returns its rank, counting from
+// zero, or -1 if it didn't make the list.
+func (rb *RecordBook) Add(rec Record) int {
+	ot, ok := GetObjectiveType(rec.Summary.ObjectiveID)
+	if !ok || !ot.Qualifies(rec.Summary) {
+		return -1
+	}
+
+	category := rec.Summary.Category
+	records := rb.Categories[category]
+	rank := len(records)
+	for i, other := range records {
+		if ot.Better(rec.Summary, other.Summary) {
+			rank = i
+			break
+		}
+	}
+	if rank >= RECORDS_PER_CATEGORY {
+		return -1
+	}
+
+	records = slices.Insert(records, rank, rec)
+	if len(records) > RECORDS_PER_CATEGORY {
+		records = records[:RECORDS_PER_CATEGORY]
+	}
+	rb.Categories[category] = records
+	return rank
+}
+
+// Best returns the top result in a category.
+func (rb *RecordBook) Best(category string) (Record, bool) {
+	records := rb.Categories[category]
+	if len(records) == 0 {
+		return Record{}, false
+	}
+	return records[0], true
+}
+
+// RelinkReplay points records at a replay that has been renamed. An empty new
+// name unlinks them from a deleted replay.
+func (rb *RecordBook) RelinkReplay(oldName, newName string) (changed bool) {
+	for _, records := range rb.Categories {
+		for i := range records {
+			if records[i].Replay == oldName {
+				records[i].Replay = newName
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// Keeps the records file in step with a replay being renamed or deleted.
+// Errors are ignored, since a stale link only means the replay can't be
+// opened from the records.
+func relinkRecords(oldName, newName string) {
+	rb, err := LoadRecordBook()
+	if err != nil {
+		return
+	}
+	if rb.RelinkReplay(oldName, newName) {
+		rb.Save()
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func sprintRecord(name string, frames int64, failed bool) Record {
+	return Record{
+		Summary: ReplaySummary{
+			ObjectiveID: LineClear,
+			Category:    "Sprint {Lines:40}",
+			Frames:      frames,
+			Failed:      failed,
+		},
+		Replay: name,
+	}
+}
+
+func TestRecordBookAdd(t *testing.T) {
+	rb := &RecordBook{Categories: make(map[string][]Record)}
+
+	for _, tc := range []struct {
+		rec  Record
+		rank int
+	}{
+		{sprintRecord("a", 3000, false), 0},
+		{sprintRecord("b", 4000, false), 1},
+		{sprintRecord("c", 2000, false), 0},
+		{sprintRecord("d", 3500, false), 2},
+		// Ties go after the result already there
+		{sprintRecord("e", 3000, false), 2},
+		// Failed games can't be a time-ranked record
+		{sprintRecord("f", 1000, true), -1},
+	} {
+		if rank := rb.Add(tc.rec); rank != tc.rank {
+			t.Errorf("%v: rank %d, want %d", tc.rec.Replay, rank, tc.rank)
+		}
+	}
+
+	var order string
+	for _, rec := range rb.Categories["Sprint {Lines:40}"] {
+		order += rec.Replay
+	}
+	if order != "caedb" {
+		t.Errorf("Records in order %q, want %q", order, "caedb")
+	}
+
+	custom := sprintRecord("custom", 100, false)
+	custom.Summary.CustomBoard = true
+	if rank := rb.Add(custom); rank != -1 {
+		t.Errorf("Game from a custom board ranked %d", rank)
+	}
+}
+
+func TestRecordBookCutOff(t *testing.T) {
+	rb := &RecordBook{Categories: make(map[string][]Record)}
+	for i := 0; i < RECORDS_PER_CATEGORY; i++ {
+		rb.Add(sprintRecord("", int64(1000+i), false))
+	}
+
+	if rank := rb.Add(sprintRecord("slow", 9999, false)); rank != -1 {
+		t.Errorf("Result slower than a full list ranked %d", rank)
+	}
+	if rank := rb.Add(sprintRecord("fast", 10, false)); rank != 0 {
+		t.Errorf("Fastest result ranked %d", rank)
+	}
+
+	records := rb.Categories["Sprint {Lines:40}"]
+	if len(records) != RECORDS_PER_CATEGORY {
+		t.Fatalf("%d records kept, want %d", len(records), RECORDS_PER_CATEGORY)
+	}
+	if last := records[len(records)-1].Summary.Frames; last != 1000+RECORDS_PER_CATEGORY-2 {
+		t.Errorf("Slowest record left is %d frames", last)
+	}
+
+	best, ok := rb.Best("Sprint {Lines:40}")
+	if !ok || best.Replay != "fast" {
+		t.Errorf("Best is %v", best)
+	}
+	if _, ok := rb.Best("Sprint {Lines:20}"); ok {
+		t.Errorf("Best found in an empty category")
+	}
+}
+
+func TestRecordBookRelinkReplay(t *testing.T) {
+	rb := &RecordBook{Categories: make(map[string][]Record)}
+	rb.Add(sprintRecord("old", 3000, false))
+	rb.Add(sprintRecord("other", 4000, false))
+
+	if !rb.RelinkReplay("old", "new") {
+		t.Errorf("Renaming a linked replay changed nothing")
+	}
+	if rb.RelinkReplay("missing", "x") {
+		t.Errorf("Renaming an unlinked replay changed something")
+	}
+	if best, _ := rb.Best("Sprint {Lines:40}"); best.Replay != "new" {
+		t.Errorf("Record links to %q after renaming", best.Replay)
+	}
+
+	rb.RelinkReplay("new", "")
+	if best, _ := rb.Best("Sprint {Lines:40}"); best.Replay != "" {
+		t.Errorf("Record links to %q after deleting", best.Replay)
+	}
+}
+
+func TestLoadRecordBookMissing(t *testing.T) {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	defer os.Chdir(wd)
+
+	// A replay that would count as a record if it were simulated
+	_, err := SaveReplay(&ReplayData{
+		Seed:              1,
+		TetrisSettings:    DefaultTetrisSettings,
+		ObjectiveID:       Endless,
+		ObjectiveSettings: &EndlessSettings{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rb, err := LoadRecordBook()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rb.Partial || len(rb.Categories) != 0 {
+		t.Errorf("Missing records loaded as %+v, want empty and partial", rb)
+	}
+
+	err = rb.Rebuild()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb, _ = LoadRecordBook()
+	if rb.Partial || len(rb.Categories["Endless"]) != 1 {
+		t.Errorf("Rebuilt records loaded as %+v", rb)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Width of the column listing categories
+const RECORDS_CATEGORY_WIDTH = 34
+
+// RecordsScene lists the best results of every objective and settings
+// combination. Picking a category moves the focus to its results, whose
+// replays can be watched.
+type RecordsScene struct {
+	app *App
+
+	records    *RecordBook
+	categories []string
+	// Receives the records once they've been rebuilt from the saved replays.
+	// Nil unless a rebuild is under way.
+	rebuilt chan *RecordBook
+
+	categoryFocus int
+	recordFocus   int
+	// Whether the focus is on the results rather than the categories
+	browsing bool
+}
+
+func (rs *RecordsScene) Init(app *App) {
+	rs.app = app
+
+	records, err := LoadRecordBook()
+	if err != nil {
+		app.ReportError("Could not load records", err)
+	}
+	rs.setRecords(records)
+
+	if records.Partial {
+		rs.rebuilt = make(chan *RecordBook, 1)
+		go rs.rebuild()
+	}
+}
+
+// Works out the records from every saved replay, away from the UI.
+func (rs *RecordsScene) rebuild() {
+	records := &RecordBook{}
+	err := records.Rebuild()
+	if err != nil {
+		rs.app.Logger.Printf("Could not rebuild records: %v\n", err)
+	}
+	rs.rebuilt <- records
+}
+
+func (rs *RecordsScene) setRecords(records *RecordBook) {
+	rs.records = records
+	rs.categories = nil
+	for category, recs := range records.Categories {
+		if len(recs) > 0 {
+			rs.categories = append(rs.categories, category)
+		}
+	}
+	slices.Sort(rs.categories)
+
+	rs.categoryFocus = min(rs.categoryFocus, max(0, len(rs.categories)-1))
+	rs.recordFocus = 0
+	rs.browsing = false
+}
+
+func (rs *RecordsScene) focusedRecords() []Record {
+	if len(rs.categories) == 0 {
+		return nil
+	}
+	return rs.records.Categories[rs.categories[rs.categoryFocus]]
+}
+
+func (rs *RecordsScene) HandleEvent(ev tcell.Event) {
+}
+
+func (rs *RecordsScene) HandleAction(act Action) {
+	if rs.browsing {
+		switch act {
+		case MoveUp:
+			rs.recordFocus = max(0, rs.recordFocus-1)
+		case MoveDown:
+			rs.recordFocus = min(len(rs.focusedRecords())-1, rs.recordFocus+1)
+		case MenuConfirm:
+			rs.WatchReplay(rs.focusedRecords()[rs.recordFocus])
+		case Quit, MoveLeft:
+			rs.browsing = false
+		}
+		return
+	}
+
+	switch act {
+	case MoveUp:
+		rs.categoryFocus = max(0, rs.categoryFocus-1)
+	case MoveDown:
+		rs.categoryFocus = min(len(rs.categories)-1, rs.categoryFocus+1)
+	case MenuConfirm, MoveRight:
+		if len(rs.focusedRecords()) > 0 {
+			rs.browsing = true
+			rs.recordFocus = 0
+		}
+	case Quit:
+		rs.app.OpenMenuScene()
+	}
+}
+
+// WatchReplay plays back the replay a record was set in.
+func (rs *RecordsScene) WatchReplay(rec Record) {
+	if rec.Replay == "" {
+		rs.app.ShowToast("This record has no replay")
+		return
+	}
+
+	replayData, err := ReadReplayFile(rec.Replay)
+	if err != nil {
+		rs.app.ReportError("Could not open replay", err)
+		return
+	}
+	rs.app.OpenReplayViewerScene(*replayData, rs)
+}
+
+func (rs *RecordsScene) Update() {
+	if rs.rebuilt == nil {
+		return
+	}
+	select {
+	case records := <-rs.rebuilt:
+		rs.rebuilt = nil
+		// Nothing to rebuild from, so the records stay as they were
+		if records.Categories != nil {
+			rs.setRecords(records)
+		}
+	default:
+	}
+}
+
+func (rs *RecordsScene) Draw(sw, sh int, rr Area, lag float64) {
+	SetString(rr.X, rr.Y, "Records", defStyle)
+
+	if rs.rebuilt != nil {
+		SetString(
+			rr.X,
+			rr.Y+2,
+			"Working out records from saved replays...",
+			defStyle.Dim(true),
+		)
+		return
+	}
+
+	if len(rs.categories) == 0 {
+		SetString(
+			rr.X,
+			rr.Y+2,
+			"No records yet. Finish a game to set one.",
+			defStyle.Dim(true),
+		)
+		return
+	}
+
+	help := "enter:results"
+	if rs.browsing {
+		help = "enter:watch replay q:back"
+	}
+	SetString(rr.X, rr.Y+1, help, defStyle.Dim(true))
+
+	header := defStyle.Underline(true)
+	SetString(rr.X+2, rr.Y+3, "Mode", header)
+	for i, category := range rs.categories {
+		y := rr.Y + 4 + i
+		style := defStyle
+		if i == rs.categoryFocus {
+			if !rs.browsing {
+				style = style.Reverse(true)
+				Screen.SetContent(rr.X, y, '*', nil, defStyle)
+			} else {
+				style = style.Bold(true)
+			}
+		}
+		SetString(rr.X+2, y, category, style)
+	}
+
+	records := rs.focusedRecords()
+	ot, _ := GetObjectiveType(records[0].Summary.ObjectiveID)
+	x := rr.X + RECORDS_CATEGORY_WIDTH
+	SetString(x+2, rr.Y+3, fmt.Sprintf("%-4v%-14v%v", "#", "Result", "Date"), header)
+	for i, rec := range records {
+		y := rr.Y + 4 + i
+		style := defStyle
+		if rs.browsing && i == rs.recordFocus {
+			style = style.Reverse(true)
+			Screen.SetContent(x, y, '*', nil, defStyle)
+		}
+		SetString(
+			x+2,
+			y,
+			fmt.Sprintf(
+				"%-4v%-14v%v",
+				i+1,
+				FormatResult(ot, rec.Summary),
+				rec.Date.Format("2006-01-02 15:04"),
+			),
+			style,
+		)
+	}
+}
+
+func (rs *RecordsScene) Cleanup() {
+}
@@ -1,26 +1,101 @@
 package main
 
 import (
+	"bufio"
 	"compress/gzip"
 	"encoding/base64"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 )
 
+// How often a hash of the game state is stored in replays
+const CHECKSUM_INTERVAL = FRAMES_PER_SECOND
+
+// Most actions, checksums or pauses a replay can hold. Far more than any real
+// game needs, but small enough that a corrupt count is caught.
+const MAX_REPLAY_ITEMS = 1 << 26
+
+// Number of values read at a time from a list in a replay
+const REPLAY_READ_CHUNK = 4096
+
 type ReplayData struct {
 	Seed              int64
 	TetrisSettings    GlobalTetrisSettings
 	ObjectiveID       ObjectiveID
 	ObjectiveSettings ObjectiveSettings
 	Actions           []ReplayAction
+	Checksums         []StateChecksum
+	Pauses            []PauseSpan
+	// Position the game started from, or nil for an empty board
+	Board *Board
+}
+
+type StateChecksum struct {
+	Frame int64
+	Hash  uint64
+}
+
+// PauseSpan records that the game was paused on a frame, and for how many
+// milliseconds of real time. Pausing doesn't advance the game, so playback
+// ignores these; they're kept so the time spent paused is known.
+type PauseSpan struct {
+	Frame  int64
+	Millis int64
+}
+
+// AppendChecksum records the state of the field if it is on a checksum frame
+// that hasn't been recorded yet.
+func AppendChecksum(checksums []StateChecksum, es *TetrisField) []StateChecksum {
+	if es.frameCount%CHECKSUM_INTERVAL != 0 {
+		return checksums
+	}
+	if len(checksums) > 0 && checksums[len(checksums)-1].Frame >= es.frameCount {
+		return checksums
+	}
+
+	return append(checksums, StateChecksum{
+		Frame: es.frameCount,
+		Hash:  es.StateHash(),
+	})
 }
 
 type ReplayEncoder func(rd *ReplayData, w io.Writer) error
 type ReplayDecoder func(r io.Reader) (*ReplayData, error)
 
 var StdEncoder ReplayEncoder = EncodeCompressed
-var StdDecoder ReplayDecoder = DecodeCompressed
+var StdDecoder ReplayDecoder = DecodeAny
+
+// Every gzip stream starts with the same bytes, which look like this once
+// base64-encoded.
+const COMPRESSED_REPLAY_PREFIX = "H4sI"
+
+// DecodeAny detects whether a replay was written as JSON or as compressed or
+// uncompressed binary, and decodes it accordingly.
+func DecodeAny(r io.Reader) (*ReplayData, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != ' ' && b[0] != '\t' && b[0] != '\r' && b[0] != '\n' {
+			break
+		}
+		br.Discard(1)
+	}
+
+	prefix, _ := br.Peek(len(COMPRESSED_REPLAY_PREFIX))
+	switch {
+	case prefix[0] == '{':
+		return DecodeJSON(br)
+	case string(prefix) == COMPRESSED_REPLAY_PREFIX:
+		return DecodeCompressed(br)
+	default:
+		return DecodeUncompressed(br)
+	}
+}
 
 func EncodeUncompressed(rd *ReplayData, w io.Writer) error {
 	base64Encoder := base64.NewEncoder(base64.StdEncoding, w)
@@ -107,27 +182,13 @@ func (rd *ReplayData) Encode(w io.Writer) error {
 	if err != nil {
 		return err
 	}
-	switch set := rd.ObjectiveSettings.(type) {
-	case *LineClearSettings:
-		err = binary.Write(w, binary.LittleEndian, set)
-		if err != nil {
-			return err
-		}
-	case *SurvivalSettings:
-		err = binary.Write(w, binary.LittleEndian, set)
-		if err != nil {
-			return err
-		}
-	case *EndlessSettings:
-		err = binary.Write(w, binary.LittleEndian, set)
-		if err != nil {
-			return err
-		}
-	case *CheeseSettings:
-		err = binary.Write(w, binary.LittleEndian, set)
-		if err != nil {
-			return err
-		}
+	ot, ok := GetObjectiveType(rd.ObjectiveID)
+	if !ok {
+		return ErrInvalidObjective
+	}
+	err = ot.Encode(rd.ObjectiveSettings, w)
+	if err != nil {
+		return err
 	}
 	err = binary.Write(
 		w,
@@ -144,9 +205,117 @@ func (rd *ReplayData) Encode(w io.Writer) error {
 			return err
 		}
 	}
+
+	err = binary.Write(
+		w,
+		binary.LittleEndian,
+		int64(len(rd.Checksums)),
+	)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(w, binary.LittleEndian, rd.Checksums)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(
+		w,
+		binary.LittleEndian,
+		int64(len(rd.Pauses)),
+	)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(w, binary.LittleEndian, rd.Pauses)
+	if err != nil {
+		return err
+	}
+
+	if rd.Board != nil {
+		return rd.Board.Encode(w)
+	}
+	return nil
+}
+
+// Encode writes the board's cells, hold piece and queue in binary.
+func (b *Board) Encode(w io.Writer) error {
+	cells := make([]int8, len(b.Grid.data))
+	for i, c := range b.Grid.data {
+		cells[i] = int8(c)
+	}
+	queue := make([]int8, len(b.Queue))
+	for i, p := range b.Queue {
+		queue[i] = int8(p)
+	}
+
+	for _, data := range []any{
+		int64(len(cells)), cells,
+		int8(b.Hold),
+		int64(len(queue)), queue,
+	} {
+		err := binary.Write(w, binary.LittleEndian, data)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// DecodeBoard reads a board written by Encode.
+func DecodeBoard(r io.Reader) (*Board, error) {
+	b := NewBoard()
+
+	var numCells int64
+	err := binary.Read(r, binary.LittleEndian, &numCells)
+	if err != nil {
+		return nil, err
+	}
+	if numCells != int64(len(b.Grid.data)) {
+		return nil, fmt.Errorf("Board has %v cells instead of %v",
+			numCells, len(b.Grid.data))
+	}
+	cells := make([]int8, numCells)
+	err = binary.Read(r, binary.LittleEndian, cells)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range cells {
+		b.Grid.data[i] = int(c)
+	}
+
+	var hold int8
+	err = binary.Read(r, binary.LittleEndian, &hold)
+	if err != nil {
+		return nil, err
+	}
+	b.Hold = int(hold)
+
+	var numQueue int64
+	err = binary.Read(r, binary.LittleEndian, &numQueue)
+	if err != nil {
+		return nil, err
+	}
+	queue, err := readSlice[int8](r, numQueue)
+	if err != nil {
+		return nil, err
+	}
+	if numQueue > 0 {
+		b.Queue = make([]int, numQueue)
+		for i, p := range queue {
+			b.Queue[i] = int(p)
+		}
+	}
+
+	err = b.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
 func (rd *ReplayData) Decode(r io.Reader) error {
 	var err error
 	err = binary.Read(r, binary.LittleEndian, &rd.Seed)
@@ -164,37 +333,13 @@ func (rd *ReplayData) Decode(r io.Reader) error {
 		return err
 	}
 
-	switch rd.ObjectiveID {
-	case LineClear:
-		var lineclear LineClearSettings
-		err = binary.Read(r, binary.LittleEndian, &lineclear)
-		if err != nil {
-			return err
-		}
-		rd.ObjectiveSettings = &lineclear
-	case Survival:
-		var survival SurvivalSettings
-		err = binary.Read(r, binary.LittleEndian, &survival)
-		if err != nil {
-			return err
-		}
-		rd.ObjectiveSettings = &survival
-	case Endless:
-		var endless EndlessSettings
-		err = binary.Read(r, binary.LittleEndian, &endless)
-		if err != nil {
-			return err
-		}
-		rd.ObjectiveSettings = &endless
-	case Cheese:
-		var cheese CheeseSettings
-		err = binary.Read(r, binary.LittleEndian, &cheese)
-		if err != nil {
-			return err
-		}
-		rd.ObjectiveSettings = &cheese
-	default:
-		return errors.New("Invalid objective ID")
+	ot, ok := GetObjectiveType(rd.ObjectiveID)
+	if !ok {
+		return ErrInvalidObjective
+	}
+	rd.ObjectiveSettings, err = ot.Decode(r)
+	if err != nil {
+		return err
 	}
 
 	var numActions int64
@@ -203,14 +348,74 @@ func (rd *ReplayData) Decode(r io.Reader) error {
 		return err
 	}
 
-	rd.Actions = make([]ReplayAction, numActions)
-	var i int64
-	for i = 0; i < numActions; i++ {
-		err = binary.Read(r, binary.LittleEndian, &rd.Actions[i])
-		if err != nil {
-			return err
+	rd.Actions, err = readSlice[ReplayAction](r, numActions)
+	if err != nil {
+		return err
+	}
+	for _, act := range rd.Actions {
+		if act.Action < 0 || int(act.Action) >= len(ActionNames) {
+			return fmt.Errorf("Invalid action %d", act.Action)
 		}
 	}
 
-	return nil
+	// Replays recorded before checksums were introduced end here
+	var numChecksums int64
+	err = binary.Read(r, binary.LittleEndian, &numChecksums)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rd.Checksums, err = readSlice[StateChecksum](r, numChecksums)
+	if err != nil {
+		return err
+	}
+
+	// Replays recorded before pausing was possible end here
+	var numPauses int64
+	err = binary.Read(r, binary.LittleEndian, &numPauses)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rd.Pauses, err = readSlice[PauseSpan](r, numPauses)
+	if err != nil {
+		return err
+	}
+
+	// Replays of games started from an empty board end here
+	rd.Board, err = DecodeBoard(r)
+	if errors.Is(err, io.EOF) {
+		rd.Board = nil
+		return nil
+	}
+	return err
+}
+
+// readSlice reads a list of count values written by binary.Write. The list
+// grows as it's read, so a corrupt count can't allocate more than the input
+// actually holds.
+func readSlice[T any](r io.Reader, count int64) ([]T, error) {
+	if count < 0 || count > MAX_REPLAY_ITEMS {
+		return nil, fmt.Errorf("Invalid count %d", count)
+	}
+
+	items := make([]T, 0, min(count, REPLAY_READ_CHUNK))
+	for int64(len(items)) < count {
+		chunk := make([]T, min(count-int64(len(items)), REPLAY_READ_CHUNK))
+		err := binary.Read(r, binary.LittleEndian, chunk)
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, chunk...)
+	}
+	return items, nil
 }
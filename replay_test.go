@@ -1,8 +1,16 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
 	"io"
+	"math"
 	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -71,3 +79,349 @@ func FuzzReplaysCompressed(f *testing.F) {
 		}
 	})
 }
+
+func TestReplayObjectiveSettings(t *testing.T) {
+	for _, ot := range ObjectiveTypes {
+		repData := ReplayData{
+			Seed:              1,
+			TetrisSettings:    DefaultTetrisSettings,
+			ObjectiveID:       ot.ID,
+			ObjectiveSettings: ot.New(),
+		}
+
+		var buf bytes.Buffer
+		err := EncodeCompressed(&repData, &buf)
+		if err != nil {
+			t.Fatalf("Could not encode %v: %v", ot.Name, err)
+		}
+
+		newRepData, err := DecodeCompressed(&buf)
+		if err != nil {
+			t.Fatalf("Could not decode %v: %v", ot.Name, err)
+		}
+
+		if newRepData.ObjectiveID != ot.ID {
+			t.Fatalf("Objective ID differs (old: %v, new: %v)", ot.ID,
+				newRepData.ObjectiveID)
+		}
+
+		if !reflect.DeepEqual(
+			repData.ObjectiveSettings,
+			newRepData.ObjectiveSettings,
+		) {
+			t.Fatalf("Objective settings differ (old: %v, new: %v)",
+				repData.ObjectiveSettings,
+				newRepData.ObjectiveSettings,
+			)
+		}
+	}
+}
+
+func TestReplayPlayerSeekBackwards(t *testing.T) {
+	rand := rand.New(rand.NewSource(1))
+	actions := make([]ReplayAction, 0)
+	for i := int64(0); i < 3600; i++ {
+		if rand.Float64() < 0.1 {
+			actions = append(actions, ReplayAction{
+				Action: []Action{
+					MoveLeft, MoveRight, RotateCW, RotateCCW,
+					HardDrop, SwapHoldPiece, ToggleSuper,
+				}[rand.Intn(7)],
+				Frame: i,
+			})
+		}
+	}
+
+	repData := ReplayData{
+		Seed:              1,
+		TetrisSettings:    DefaultTetrisSettings,
+		ObjectiveID:       Endless,
+		ObjectiveSettings: &EndlessSettings{},
+		Actions:           actions,
+	}
+
+	seeked := NewReplayPlayer(repData)
+	seeked.SeekToFrame(3000)
+	seeked.SeekToFrame(1500)
+
+	direct := NewReplayPlayer(repData)
+	direct.SeekToFrame(1500)
+
+	if seeked.Frame() != direct.Frame() {
+		t.Fatalf("Frame differs (seeked: %v, direct: %v)", seeked.Frame(),
+			direct.Frame())
+	}
+
+	if !reflect.DeepEqual(seeked.Field.grid, direct.Field.grid) ||
+		seeked.Field.score != direct.Field.score ||
+		seeked.Field.pieceCount != direct.Field.pieceCount {
+		t.Fatalf("Field state differs after seeking backwards")
+	}
+}
+
+func TestReplayFormats(t *testing.T) {
+	repData := ReplayData{
+		Seed:              42,
+		TetrisSettings:    DefaultTetrisSettings,
+		ObjectiveID:       Cheese,
+		ObjectiveSettings: &CheeseSettings{Garbage: 18, Endless: true},
+		Actions: []ReplayAction{
+			{Action: MoveLeft, Frame: 3},
+			{Action: HardDrop, Frame: 10},
+			{Action: SwapHoldPiece, Frame: 10},
+		},
+		Checksums: []StateChecksum{
+			{Frame: 60, Hash: 1234},
+		},
+		Pauses: []PauseSpan{
+			{Frame: 45, Millis: 2500},
+		},
+		Board: &Board{
+			Grid: MakeGridWith(BOARD_WIDTH, BOARD_HEIGHT, func(x, y int) int {
+				return (x + y) % 9
+			}),
+			Hold:  5,
+			Queue: []int{0, 3, 6},
+		},
+	}
+
+	encoders := map[string]ReplayEncoder{
+		"json":         EncodeJSON,
+		"compressed":   EncodeCompressed,
+		"uncompressed": EncodeUncompressed,
+	}
+
+	for name, encode := range encoders {
+		var buf bytes.Buffer
+		err := encode(&repData, &buf)
+		if err != nil {
+			t.Fatalf("Could not encode %v: %v", name, err)
+		}
+
+		newRepData, err := StdDecoder(&buf)
+		if err != nil {
+			t.Fatalf("Could not decode %v: %v", name, err)
+		}
+
+		if !reflect.DeepEqual(&repData, newRepData) {
+			t.Fatalf("Replay differs after %v round trip (old: %v, new: %v)",
+				name, repData, *newRepData)
+		}
+	}
+}
+
+func TestReplayChecksums(t *testing.T) {
+	repData := ReplayData{
+		Seed:              3,
+		TetrisSettings:    DefaultTetrisSettings,
+		ObjectiveID:       LineClear,
+		ObjectiveSettings: &LineClearSettings{Lines: 40},
+		Actions: []ReplayAction{
+			{Action: MoveLeft, Frame: 30},
+			{Action: HardDrop, Frame: 90},
+			{Action: RotateCW, Frame: 150},
+			{Action: HardDrop, Frame: 200},
+		},
+	}
+
+	repData.Checksums = repData.Simulate().Checksums
+	if len(repData.Checksums) == 0 {
+		t.Fatalf("No checksums were recorded")
+	}
+
+	err := repData.Verify()
+	if err != nil {
+		t.Fatalf("Unmodified replay failed to verify: %v", err)
+	}
+
+	// Change the last action so the game diverges after frame 200
+	repData.Actions[3].Action = MoveRight
+	err = repData.Verify()
+	desync, ok := err.(*DesyncError)
+	if !ok {
+		t.Fatalf("Modified replay did not desync (err: %v)", err)
+	}
+	if desync.LastGoodFrame > 200 || desync.Frame <= 200 {
+		t.Fatalf("Desync reported in the wrong place: %v", desync)
+	}
+}
+
+// Encodes a replay without actions, checksums or pauses, and returns it
+// without the three counts at the end.
+func replayHeader(t testing.TB) []byte {
+	repData := ReplayData{
+		Seed:              5,
+		TetrisSettings:    DefaultTetrisSettings,
+		ObjectiveID:       LineClear,
+		ObjectiveSettings: &LineClearSettings{Lines: 40},
+	}
+	var buf bytes.Buffer
+	err := repData.Encode(&buf)
+	if err != nil {
+		t.Fatalf("Could not encode: %v", err)
+	}
+	return buf.Bytes()[:buf.Len()-3*8]
+}
+
+func TestReplayCorruptCounts(t *testing.T) {
+	for _, counts := range [][]int64{
+		{-1},
+		{1 << 62},
+		{MAX_REPLAY_ITEMS + 1},
+		{1000},
+		{0, -5},
+		{0, 1 << 40},
+		{0, 0, 1 << 50},
+		{0, 0, -1},
+	} {
+		buf := bytes.NewBuffer(slices.Clone(replayHeader(t)))
+		for _, count := range counts {
+			binary.Write(buf, binary.LittleEndian, count)
+		}
+
+		var rd ReplayData
+		if err := rd.Decode(buf); err == nil {
+			t.Errorf("Counts %v decoded without an error", counts)
+		}
+	}
+}
+
+func TestReplayInvalidAction(t *testing.T) {
+	buf := bytes.NewBuffer(slices.Clone(replayHeader(t)))
+	binary.Write(buf, binary.LittleEndian, int64(1))
+	binary.Write(buf, binary.LittleEndian, ReplayAction{Action: 100, Frame: 1})
+
+	var rd ReplayData
+	if err := rd.Decode(buf); err == nil {
+		t.Errorf("Invalid action decoded without an error")
+	}
+}
+
+func FuzzReplayDecode(f *testing.F) {
+	header := replayHeader(f)
+	f.Add(header)
+	f.Add(append(slices.Clone(header), make([]byte, 24)...))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Anything may fail to decode, but nothing may panic
+		var rd ReplayData
+		rd.Decode(bytes.NewReader(data))
+	})
+}
+
+func TestDecodeBoardInvalid(t *testing.T) {
+	valid := NewBoard()
+	valid.Grid.Set(0, BOARD_HEIGHT-1, GARBAGE_CELL)
+	valid.Hold = 6
+	valid.Queue = []int{0, 6}
+
+	for _, tc := range []struct {
+		name   string
+		change func(b *Board)
+	}{
+		{"cell past garbage", func(b *Board) { b.Grid.Set(3, 3, GARBAGE_CELL+1) }},
+		{"negative cell", func(b *Board) { b.Grid.Set(3, 3, -1) }},
+		{"hold past the pieces", func(b *Board) { b.Hold = len(Pieces) }},
+		{"negative hold", func(b *Board) { b.Hold = -1 }},
+		{"queue past the pieces", func(b *Board) { b.Queue = []int{0, len(Pieces)} }},
+	} {
+		b := valid.Clone()
+		tc.change(b)
+		var buf bytes.Buffer
+		b.Encode(&buf)
+		if _, err := DecodeBoard(&buf); err == nil {
+			t.Errorf("%v: decoded without an error", tc.name)
+		}
+	}
+
+	var buf bytes.Buffer
+	valid.Encode(&buf)
+	// Claim a huge queue in place of the real one
+	data := buf.Bytes()[:buf.Len()-len(valid.Queue)-8]
+	data = binary.LittleEndian.AppendUint64(slices.Clone(data), 1<<60)
+	if _, err := DecodeBoard(bytes.NewReader(data)); err == nil {
+		t.Errorf("Huge queue decoded without an error")
+	}
+
+	decoded, err := DecodeBoard(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Valid board: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, valid) {
+		t.Errorf("Decoded %+v, want %+v", decoded, valid)
+	}
+}
+
+func TestExportAsciicast(t *testing.T) {
+	repData := ReplayData{
+		Seed:              7,
+		TetrisSettings:    DefaultTetrisSettings,
+		ObjectiveID:       Endless,
+		ObjectiveSettings: &EndlessSettings{},
+		Actions: []ReplayAction{
+			{Action: MoveLeft, Frame: 20},
+			{Action: HardDrop, Frame: 30},
+		},
+	}
+	const frameSkip = 30
+
+	var buf bytes.Buffer
+	err := ExportAsciicast(&repData, &buf, frameSkip)
+	if err != nil {
+		t.Fatalf("Could not export: %v", err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(nil, 1<<20)
+	if !scanner.Scan() {
+		t.Fatalf("Empty recording")
+	}
+	var header castHeader
+	err = json.Unmarshal(scanner.Bytes(), &header)
+	if err != nil {
+		t.Fatalf("Could not decode header: %v", err)
+	}
+	if header.Version != 2 ||
+		header.Width != CAST_WIDTH ||
+		header.Height != CAST_HEIGHT {
+		t.Errorf("Header %+v", header)
+	}
+
+	var times []float64
+	var outputs []string
+	for scanner.Scan() {
+		var event []any
+		err = json.Unmarshal(scanner.Bytes(), &event)
+		if err != nil {
+			t.Fatalf("Could not decode event %d: %v", len(times), err)
+		}
+		if len(event) != 3 || event[1] != "o" {
+			t.Fatalf("Event %d is %v", len(times), event)
+		}
+		times = append(times, event[0].(float64))
+		outputs = append(outputs, event[2].(string))
+	}
+
+	// One frame every frameSkip frames, then the final frame, then the hold
+	last := 30 + REPLAY_TRAILING_FRAMES + 1
+	frames := int(last/frameSkip) + 1
+	if len(times) != frames+2 {
+		t.Fatalf("%d events, want %d", len(times), frames+2)
+	}
+	for i := 0; i < frames; i++ {
+		want := float64(i*frameSkip) * UPDATE_TICK_RATE_MS / 1000
+		if math.Abs(times[i]-want) > 1e-9 {
+			t.Errorf("Frame %d at %vs, want %vs", i, times[i], want)
+		}
+		if !strings.HasPrefix(outputs[i], "\x1b[H") {
+			t.Errorf("Frame %d doesn't start at the top left", i)
+		}
+	}
+	end := float64(last) * UPDATE_TICK_RATE_MS / 1000
+	if math.Abs(times[frames]-end) > 1e-9 ||
+		math.Abs(times[frames+1]-end-CAST_END_HOLD_SECS) > 1e-9 ||
+		outputs[frames+1] != "" {
+		t.Errorf("Recording ends with %v", times[frames:])
+	}
+}
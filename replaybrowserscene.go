@@ -1,84 +1,446 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"os"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// Rows taken up by the title, help text and column headers
+const REPLAY_BROWSER_HEADER_ROWS = 3
+
+type ReplayColumn int8
+
+const (
+	FavoriteColumn ReplayColumn = iota
+	ModeColumn
+	DateColumn
+	TimeColumn
+	ScoreColumn
+	LinesColumn
+	ResultColumn
+	SizeColumn
+)
+
+var REPLAY_COLUMNS = []struct {
+	Name  string
+	Width int
+}{
+	{"*", 1},
+	{"Mode", 12},
+	{"Date", 16},
+	{"Time", 9},
+	{"Score", 8},
+	{"Lines", 5},
+	{"Result", 7},
+	{"Size", 6},
+}
+
+type OutcomeFilter int8
+
+const (
+	AnyOutcome OutcomeFilter = iota
+	OutcomeCompleted
+	OutcomeFailed
+)
+
+var OUTCOME_FILTER_NAMES = []string{
+	"Any",
+	"Completed",
+	"Failed",
+}
+
+// Longest name that can be typed in when renaming a replay
+const REPLAY_NAME_MAX_LENGTH = 48
+
+// Actions that ask for input before they are carried out
+type BrowserPrompt int8
+
+const (
+	NoPrompt BrowserPrompt = iota
+	DeletePrompt
+	RenamePrompt
+	PrunePrompt
 )
 
 type ReplayBrowserScene struct {
 	app *App
 
-	replayFileNames []string
-	loaded          bool
-	menuFocus       int
+	// Guards entries, loaded, loadErr and dirty, which the loader goroutine
+	// writes to
+	mu      sync.Mutex
+	entries []*ReplayEntry
+	loaded  bool
+	loadErr error
+	dirty   bool
+	done    chan struct{}
+
+	// Entries that pass the filters, in sorted order
+	view []*ReplayEntry
+
+	index *ReplayIndex
+
+	prompt      BrowserPrompt
+	renameField FormField
+	renameValue string
+	pruneList   []*ReplayEntry
+
+	// Result of the last action, shown in place of the help text
+	status string
+
+	sortColumn  ReplayColumn
+	sortReverse bool
+	modeFilter  int
+	outcome     OutcomeFilter
+
+	menuFocus int
+	scroll    int
+
+	// Rows available for entries when last drawn
+	visibleRows int
 }
 
 func (ms *ReplayBrowserScene) Init(app *App) {
 	ms.app = app
+	ms.done = make(chan struct{})
+	ms.sortColumn = DateColumn
+	ms.sortReverse = true
+	ms.modeFilter = -1
+	ms.visibleRows = MIN_HEIGHT - REPLAY_BROWSER_HEADER_ROWS
 
-	go func() {
-		replayDir, err := os.Open("replays")
-		defer replayDir.Close()
-		if err != nil {
+	var err error
+	ms.index, err = LoadReplayIndex()
+	if err != nil {
+		ms.app.ReportError("Could not load favorites", err)
+	}
+
+	go ms.loadEntries()
+}
+
+// Lists the replay directory, then decodes each replay in turn so the
+// metadata columns fill in without blocking the UI. The newest replays are
+// decoded first.
+func (ms *ReplayBrowserScene) loadEntries() {
+	entries, err := ListReplays()
+
+	ms.mu.Lock()
+	ms.entries = entries
+	ms.loaded = true
+	ms.loadErr = err
+	ms.dirty = true
+	ms.mu.Unlock()
+
+	for _, entry := range entries {
+		select {
+		case <-ms.done:
 			return
+		default:
 		}
-		entries, err := replayDir.ReadDir(0)
-		if err != nil {
-			return
+
+		// The entry may be renamed while it is being decoded
+		ms.mu.Lock()
+		name := entry.Name
+		ms.mu.Unlock()
+
+		summary, err := SummarizeReplay(name)
+
+		ms.mu.Lock()
+		entry.Decoded = true
+		entry.Err = err
+		entry.Summary = summary
+		ms.dirty = true
+		ms.mu.Unlock()
+	}
+
+	ms.rebuildRecords(entries)
+}
+
+// Works out the records from the replays just decoded, if they were never
+// worked out before. Runs on the loader goroutine, so failures are only
+// logged.
+func (ms *ReplayBrowserScene) rebuildRecords(entries []*ReplayEntry) {
+	records, err := LoadRecordBook()
+	if err != nil || !records.Partial {
+		return
+	}
+
+	ms.mu.Lock()
+	decoded := make([]*ReplayEntry, len(entries))
+	for i, entry := range entries {
+		clone := *entry
+		decoded[i] = &clone
+	}
+	ms.mu.Unlock()
+
+	err = records.RebuildFrom(decoded)
+	if err != nil {
+		ms.app.Logger.Printf("Could not rebuild records: %v\n", err)
+	}
+}
+
+// Rebuilds the filtered and sorted view, keeping the focus on the same entry.
+// Must be called with the lock held.
+func (ms *ReplayBrowserScene) rebuildView() {
+	var focused *ReplayEntry
+	if ms.menuFocus < len(ms.view) {
+		focused = ms.view[ms.menuFocus]
+	}
+
+	ms.view = ms.view[:0]
+	for _, entry := range ms.entries {
+		if ms.matchesFilters(entry) {
+			ms.view = append(ms.view, entry)
+		}
+	}
+
+	slices.SortStableFunc(ms.view, func(a, b *ReplayEntry) int {
+		// Replays that haven't been decoded yet always go last
+		if a.Decoded != b.Decoded && ms.sortColumn != FavoriteColumn &&
+			ms.sortColumn != DateColumn && ms.sortColumn != SizeColumn {
+			if a.Decoded {
+				return -1
+			}
+			return 1
 		}
-		names := make([]string, len(entries))
-		for i, e := range entries {
-			names[i] = e.Name()
+
+		cmp := ms.compareEntries(a, b)
+		if ms.sortReverse {
+			cmp = -cmp
+		}
+		return cmp
+	})
+
+	ms.menuFocus = max(0, min(len(ms.view)-1, ms.menuFocus))
+	if focused != nil {
+		if idx := slices.Index(ms.view, focused); idx >= 0 {
+			ms.menuFocus = idx
 		}
+	}
+	ms.scrollToFocus()
+
+	ms.dirty = false
+}
 
-		ms.replayFileNames = names
-		slices.SortFunc(ms.replayFileNames, func(a string, b string) int {
-			return strings.Compare(b, a)
-		})
-		ms.loaded = true
-	}()
+func (ms *ReplayBrowserScene) matchesFilters(entry *ReplayEntry) bool {
+	if ms.modeFilter < 0 && ms.outcome == AnyOutcome {
+		return true
+	}
+	// Filters can only be checked once the replay has been decoded
+	if !entry.Decoded || entry.Err != nil {
+		return false
+	}
+
+	if ms.modeFilter >= 0 &&
+		entry.Summary.ObjectiveID != ObjectiveTypes[ms.modeFilter].ID {
+		return false
+	}
+
+	switch ms.outcome {
+	case OutcomeCompleted:
+		return !entry.Summary.Failed
+	case OutcomeFailed:
+		return entry.Summary.Failed
+	}
+
+	return true
+}
+
+func (ms *ReplayBrowserScene) compareEntries(a, b *ReplayEntry) int {
+	switch ms.sortColumn {
+	case FavoriteColumn:
+		return compareBools(
+			ms.index.Favorites[a.Name],
+			ms.index.Favorites[b.Name],
+		)
+	case ModeColumn:
+		return strings.Compare(
+			a.Summary.ObjectiveID.String(),
+			b.Summary.ObjectiveID.String(),
+		)
+	case TimeColumn:
+		return compareInts(a.Summary.Frames, b.Summary.Frames)
+	case ScoreColumn:
+		return compareInts(a.Summary.Score, b.Summary.Score)
+	case LinesColumn:
+		return compareInts(a.Summary.Lines, b.Summary.Lines)
+	case ResultColumn:
+		return compareBools(a.Summary.Failed, b.Summary.Failed)
+	case SizeColumn:
+		return compareInts(a.Size, b.Size)
+	default:
+		return a.ModTime.Compare(b.ModTime)
+	}
+}
+
+func compareInts(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareBools(a, b bool) int {
+	if a == b {
+		return 0
+	} else if a {
+		return 1
+	}
+	return -1
+}
+
+func (ms *ReplayBrowserScene) scrollToFocus() {
+	if ms.menuFocus < ms.scroll {
+		ms.scroll = ms.menuFocus
+	}
+	if ms.menuFocus >= ms.scroll+ms.visibleRows {
+		ms.scroll = ms.menuFocus - ms.visibleRows + 1
+	}
+	ms.scroll = max(0, ms.scroll)
+}
+
+func (ms *ReplayBrowserScene) moveFocus(delta int) {
+	ms.menuFocus = max(0, min(len(ms.view)-1, ms.menuFocus+delta))
+	ms.scrollToFocus()
 }
 
 func (ms *ReplayBrowserScene) HandleEvent(evt tcell.Event) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	switch ms.prompt {
+	case RenamePrompt:
+		if evt, ok := evt.(*tcell.EventKey); ok && evt.Key() == tcell.KeyCtrlQ {
+			ms.prompt = NoPrompt
+			return
+		}
+		ms.renameField.Field.HandleInput(evt)
+		return
+	case DeletePrompt, PrunePrompt:
+		if evt, ok := evt.(*tcell.EventKey); ok {
+			if IsRune(evt, 'y') || IsRune(evt, 'Y') {
+				ms.ConfirmPrompt()
+			} else if IsRune(evt, 'n') || IsRune(evt, 'N') {
+				ms.prompt = NoPrompt
+			}
+		}
+		return
+	}
+
+	switch evt := evt.(type) {
+	case *tcell.EventKey:
+		ms.status = ""
+		switch evt.Key() {
+		case tcell.KeyPgUp:
+			ms.moveFocus(-ms.visibleRows)
+		case tcell.KeyPgDn:
+			ms.moveFocus(ms.visibleRows)
+		case tcell.KeyRune:
+			switch evt.Rune() {
+			case 'g', 'G':
+				if entry := ms.focusedEntry(); entry != nil {
+					if replayData := ms.LoadReplay(entry); replayData != nil {
+						ms.app.OpenGhostRaceScene(*replayData)
+					}
+				}
+			case 's', 'S':
+				ms.sortReverse = !ms.sortReverse
+				ms.rebuildView()
+			case 'm', 'M':
+				ms.modeFilter++
+				if ms.modeFilter == len(ObjectiveTypes) {
+					ms.modeFilter = -1
+				}
+				ms.rebuildView()
+			case 'o', 'O':
+				ms.outcome = (ms.outcome + 1) % OutcomeFilter(len(OUTCOME_FILTER_NAMES))
+				ms.rebuildView()
+			case 'f', 'F':
+				ms.ToggleFavorite()
+			case 'n', 'N':
+				ms.OpenRenamePrompt()
+			case 'd', 'D':
+				if ms.focusedEntry() != nil {
+					ms.prompt = DeletePrompt
+				}
+			case 'P':
+				ms.OpenPrunePrompt()
+			}
+		}
+	}
 }
 
 func (ms *ReplayBrowserScene) HandleAction(act Action) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	// Keys typed into a prompt are handled as events, since most letters are
+	// also bound to actions
+	if ms.prompt != NoPrompt {
+		if act == MenuConfirm && ms.prompt == RenamePrompt {
+			ms.ConfirmPrompt()
+		}
+		return
+	}
+
 	switch act {
 	case Quit:
 		ms.app.OpenMenuScene()
 	case MoveUp:
-		if ms.loaded {
-			ms.menuFocus = max(0, ms.menuFocus-1)
-		}
+		ms.moveFocus(-1)
 	case MoveDown:
-		if ms.loaded {
-			ms.menuFocus = min(len(ms.replayFileNames), ms.menuFocus+1)
-		}
+		ms.moveFocus(1)
+	case MoveLeft:
+		ms.sortColumn = max(0, ms.sortColumn-1)
+		ms.rebuildView()
+	case MoveRight:
+		ms.sortColumn = min(ReplayColumn(len(REPLAY_COLUMNS)-1), ms.sortColumn+1)
+		ms.rebuildView()
 	case MenuConfirm:
-		if ms.loaded {
-			ms.ConfirmAction()
-		}
+		ms.ConfirmAction()
 	}
 }
 
 func (ms *ReplayBrowserScene) Update() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	// Errors are reported from here since the app isn't safe to use from the
+	// loader goroutine
+	if ms.loadErr != nil {
+		ms.app.ReportError("Could not list replays", ms.loadErr)
+		ms.loadErr = nil
+	}
+
+	if ms.dirty {
+		ms.rebuildView()
+	}
 }
 
-func (ms *ReplayBrowserScene) ConfirmAction() {
-	name := ms.replayFileNames[ms.menuFocus]
-	file, err := os.Open(fmt.Sprintf("replays/%s", name))
-	if err != nil {
-		panic(err)
+// Must be called with the lock held.
+func (ms *ReplayBrowserScene) focusedEntry() *ReplayEntry {
+	if ms.menuFocus < 0 || ms.menuFocus >= len(ms.view) {
+		return nil
 	}
-	defer file.Close()
-	replayData, err := StdDecoder(file)
+	return ms.view[ms.menuFocus]
+}
 
+// LoadReplay reads the full replay behind an entry. If that fails, the entry
+// is marked as broken and nil is returned. Must be called with the lock held.
+func (ms *ReplayBrowserScene) LoadReplay(entry *ReplayEntry) *ReplayData {
+	replayData, err := ReadReplayFile(entry.Name)
 	if err != nil {
-		panic(err)
+		ms.app.ReportError(fmt.Sprintf("Could not open %v", entry.Name), err)
+		entry.Decoded = true
+		entry.Err = err
+		ms.rebuildView()
+		return nil
 	}
 
 	ms.app.Logger.Printf("Seed: %v\n", replayData.Seed)
@@ -90,36 +452,315 @@ func (ms *ReplayBrowserScene) ConfirmAction() {
 	)
 	ms.app.Logger.Printf("Number of actions: %v\n", len(replayData.Actions))
 
-	ms.app.OpenReplayViewerScene(*replayData)
+	return replayData
+}
+
+// Must be called with the lock held.
+func (ms *ReplayBrowserScene) ConfirmAction() {
+	entry := ms.focusedEntry()
+	if entry == nil {
+		return
+	}
+
+	if replayData := ms.LoadReplay(entry); replayData != nil {
+		ms.app.OpenReplayViewerScene(*replayData, nil)
+	}
+}
+
+// Must be called with the lock held.
+func (ms *ReplayBrowserScene) ToggleFavorite() {
+	entry := ms.focusedEntry()
+	if entry == nil {
+		return
+	}
+
+	err := ms.index.ToggleFavorite(entry.Name)
+	if err != nil {
+		ms.app.ReportError("Could not save favorites", err)
+	}
+	ms.rebuildView()
+}
+
+// Must be called with the lock held.
+func (ms *ReplayBrowserScene) OpenRenamePrompt() {
+	entry := ms.focusedEntry()
+	if entry == nil {
+		return
+	}
+
+	ms.prompt = RenamePrompt
+	ms.renameValue = entry.Name
+	ms.renameField = NewTextField(
+		"Rename to",
+		entry.Name,
+		REPLAY_NAME_MAX_LENGTH,
+		func(value string) {
+			ms.renameValue = value
+		},
+	)
+}
+
+// Works out which replays would be pruned. Every replay has to be decoded
+// first so that personal bests are known. Must be called with the lock held.
+func (ms *ReplayBrowserScene) OpenPrunePrompt() {
+	for _, entry := range ms.entries {
+		if !entry.Decoded {
+			ms.status = "Wait for all replays to load before pruning"
+			return
+		}
+	}
+
+	ms.pruneList = ms.index.PlanPrune(ms.entries, DEFAULT_PRUNE_KEEP)
+	if len(ms.pruneList) == 0 {
+		ms.status = "Nothing to prune"
+		return
+	}
+	ms.prompt = PrunePrompt
+}
+
+// Carries out the action the open prompt is asking about. Must be called with
+// the lock held.
+func (ms *ReplayBrowserScene) ConfirmPrompt() {
+	prompt := ms.prompt
+	ms.prompt = NoPrompt
+
+	switch prompt {
+	case DeletePrompt:
+		entry := ms.focusedEntry()
+		if entry == nil {
+			return
+		}
+		err := ms.index.DeleteReplay(entry.Name)
+		if err != nil {
+			ms.app.ReportError(fmt.Sprintf("Could not delete %v", entry.Name), err)
+			return
+		}
+		ms.removeEntries(entry)
+		ms.status = fmt.Sprintf("Deleted %v", entry.Name)
+	case RenamePrompt:
+		entry := ms.focusedEntry()
+		if entry == nil || ms.renameValue == entry.Name {
+			return
+		}
+		err := ms.index.RenameReplay(entry.Name, ms.renameValue)
+		if err != nil {
+			ms.app.ReportError(fmt.Sprintf("Could not rename %v", entry.Name), err)
+			return
+		}
+		entry.Name = ms.renameValue
+		ms.status = fmt.Sprintf("Renamed to %v", entry.Name)
+	case PrunePrompt:
+		deleted := make([]*ReplayEntry, 0, len(ms.pruneList))
+		for _, entry := range ms.pruneList {
+			err := ms.index.DeleteReplay(entry.Name)
+			if err != nil {
+				ms.app.ReportError(fmt.Sprintf("Could not delete %v", entry.Name), err)
+				continue
+			}
+			deleted = append(deleted, entry)
+		}
+		ms.removeEntries(deleted...)
+		ms.status = fmt.Sprintf(
+			"Pruned %d of %d replays",
+			len(deleted),
+			len(ms.pruneList),
+		)
+		ms.pruneList = nil
+	}
+}
+
+// Must be called with the lock held.
+func (ms *ReplayBrowserScene) removeEntries(removed ...*ReplayEntry) {
+	ms.entries = slices.DeleteFunc(ms.entries, func(entry *ReplayEntry) bool {
+		return slices.Contains(removed, entry)
+	})
+	ms.view = slices.DeleteFunc(ms.view, func(entry *ReplayEntry) bool {
+		return slices.Contains(removed, entry)
+	})
+	ms.rebuildView()
+}
+
+func formatFileSize(size int64) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf("%dB", size)
+	case size < 1024*1024:
+		return fmt.Sprintf("%.1fK", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%.1fM", float64(size)/(1024*1024))
+	}
+}
+
+func (entry *ReplayEntry) Columns(favorite bool) []string {
+	date := entry.ModTime.Format("2006-01-02 15:04")
+	size := formatFileSize(entry.Size)
+	star := ""
+	if favorite {
+		star = "*"
+	}
+
+	if !entry.Decoded {
+		return []string{star, "...", date, "", "", "", "", size}
+	}
+	if errors.Is(entry.Err, ErrCorruptReplay) {
+		return []string{star, "?", date, "", "", "", "Corrupt", size}
+	} else if entry.Err != nil {
+		return []string{star, "?", date, "", "", "", "Error", size}
+	}
+
+	result := "Clear"
+	if entry.Summary.Failed {
+		result = "Failed"
+	}
+
+	return []string{
+		star,
+		entry.Summary.ObjectiveID.String(),
+		date,
+		FormatFrames(entry.Summary.Frames),
+		fmt.Sprint(entry.Summary.Score),
+		fmt.Sprint(entry.Summary.Lines),
+		result,
+		size,
+	}
+}
+
+func drawColumns(x, y int, columns []string, style tcell.Style) {
+	for i, col := range REPLAY_COLUMNS {
+		text := []rune(columns[i])
+		if len(text) > col.Width {
+			text = text[:col.Width]
+		}
+		SetString(x, y, string(text), style)
+		x += col.Width + 1
+	}
 }
 
 func (ms *ReplayBrowserScene) Draw(sw, sh int, rr Area, lag float64) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.visibleRows = rr.Height - REPLAY_BROWSER_HEADER_ROWS
+
 	SetString(
 		rr.X,
 		rr.Y,
 		"Replays",
 		defStyle)
 
-	if ms.loaded {
-		for i, opt := range ms.replayFileNames {
-			style := defStyle
-			if i == ms.menuFocus {
-				style = style.Reverse(true)
-				Screen.SetContent(
-					rr.X,
-					rr.Y+2+2*i,
-					'*',
-					nil, defStyle)
+	modeName := "Any"
+	if ms.modeFilter >= 0 {
+		modeName = ObjectiveTypes[ms.modeFilter].Name
+	}
+	SetStringArray(
+		rr.Right()-1,
+		rr.Y,
+		defStyle,
+		true,
+		fmt.Sprintf(
+			"Mode: %v  Outcome: %v",
+			modeName,
+			OUTCOME_FILTER_NAMES[ms.outcome],
+		),
+	)
+	ms.drawPrompt(rr)
+
+	// Column headers, with the sort column highlighted
+	headers := make([]string, len(REPLAY_COLUMNS))
+	for i, col := range REPLAY_COLUMNS {
+		headers[i] = col.Name
+		if ReplayColumn(i) == ms.sortColumn {
+			if ms.sortReverse {
+				headers[i] += "v"
+			} else {
+				headers[i] += "^"
 			}
+		}
+	}
+	drawColumns(rr.X+2, rr.Y+2, headers, defStyle.Underline(true))
+
+	if !ms.loaded {
+		SetString(rr.X+2, rr.Y+REPLAY_BROWSER_HEADER_ROWS, "Loading...", defStyle)
+		return
+	}
+	if len(ms.view) == 0 {
+		SetString(rr.X+2, rr.Y+REPLAY_BROWSER_HEADER_ROWS, "No replays", defStyle)
+		return
+	}
 
+	for row := 0; row < ms.visibleRows; row++ {
+		i := ms.scroll + row
+		if i >= len(ms.view) {
+			break
+		}
+
+		y := rr.Y + REPLAY_BROWSER_HEADER_ROWS + row
+		style := defStyle
+		if i == ms.menuFocus {
+			style = style.Reverse(true)
+			Screen.SetContent(rr.X, y, '*', nil, defStyle)
+		}
+
+		entry := ms.view[i]
+		drawColumns(rr.X+2, y, entry.Columns(ms.index.Favorites[entry.Name]), style)
+	}
+
+	// Scroll indicators
+	if ms.scroll > 0 {
+		Screen.SetContent(rr.Right()-1, rr.Y+REPLAY_BROWSER_HEADER_ROWS, '^', nil, defStyle)
+	}
+	if ms.scroll+ms.visibleRows < len(ms.view) {
+		Screen.SetContent(rr.Right()-1, rr.Bottom()-1, 'v', nil, defStyle)
+	}
+}
+
+// Draws the open prompt, or the help text if there isn't one.
+func (ms *ReplayBrowserScene) drawPrompt(rr Area) {
+	x, y := rr.X, rr.Y+1
+	promptStyle := defStyle.Reverse(true)
+
+	switch ms.prompt {
+	case DeletePrompt:
+		if entry := ms.focusedEntry(); entry != nil {
+			SetString(x, y, fmt.Sprintf("Delete %v? y/n", entry.Name), promptStyle)
+		}
+	case PrunePrompt:
+		SetString(
+			x, y,
+			fmt.Sprintf(
+				"Delete %d replays, keeping favorites, bests and the newest %d per mode? y/n",
+				len(ms.pruneList),
+				DEFAULT_PRUNE_KEEP,
+			),
+			promptStyle,
+		)
+	case RenamePrompt:
+		SetString(x, y, ms.renameField.Name, promptStyle)
+		ms.renameField.Field.Draw(
+			x+1+runewidth.StringWidth(ms.renameField.Name),
+			y,
+			true,
+		)
+		SetStringArray(
+			rr.Right()-1,
+			y,
+			defStyle.Dim(true),
+			true,
+			"enter:apply ctrl+q:cancel",
+		)
+	default:
+		if ms.status != "" {
+			SetString(x, y, ms.status, defStyle)
+		} else {
 			SetString(
-				rr.X+2,
-				rr.Y+2+2*i,
-				opt,
-				style)
+				x, y,
+				"<>:sort s:rev m:mode o:outcome g:ghost f:fav n:rename d:del P:prune",
+				defStyle.Dim(true),
+			)
 		}
 	}
 }
 
 func (ms *ReplayBrowserScene) Cleanup() {
+	close(ms.done)
 }
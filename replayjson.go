@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const REPLAY_JSON_VERSION = 1
+
+// Human-readable form of ReplayData. The objective is stored by name and its
+// settings as a JSON object, so the file can be read and edited by hand.
+type replayJSON struct {
+	Version           int
+	Seed              int64
+	TetrisSettings    GlobalTetrisSettings
+	Objective         string
+	ObjectiveSettings json.RawMessage
+	Actions           []ReplayAction
+	Checksums         []StateChecksum `json:",omitempty"`
+	Pauses            []PauseSpan     `json:",omitempty"`
+	Board             *Board          `json:",omitempty"`
+}
+
+func EncodeJSON(rd *ReplayData, w io.Writer) error {
+	ot, ok := GetObjectiveType(rd.ObjectiveID)
+	if !ok {
+		return ErrInvalidObjective
+	}
+
+	settings, err := json.Marshal(rd.ObjectiveSettings)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(replayJSON{
+		Version:           REPLAY_JSON_VERSION,
+		Seed:              rd.Seed,
+		TetrisSettings:    rd.TetrisSettings,
+		Objective:         ot.Name,
+		ObjectiveSettings: settings,
+		Actions:           rd.Actions,
+		Checksums:         rd.Checksums,
+		Pauses:            rd.Pauses,
+		Board:             rd.Board,
+	})
+}
+
+func DecodeJSON(r io.Reader) (*ReplayData, error) {
+	var data replayJSON
+	err := json.NewDecoder(r).Decode(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Version != REPLAY_JSON_VERSION {
+		return nil, fmt.Errorf("Unsupported replay version %v", data.Version)
+	}
+
+	ot, ok := GetObjectiveTypeByName(data.Objective)
+	if !ok {
+		return nil, fmt.Errorf("Invalid objective %q", data.Objective)
+	}
+
+	settings := ot.New()
+	if len(data.ObjectiveSettings) > 0 {
+		err = json.Unmarshal(data.ObjectiveSettings, settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if data.Actions == nil {
+		data.Actions = make([]ReplayAction, 0)
+	}
+
+	return &ReplayData{
+		Seed:              data.Seed,
+		TetrisSettings:    data.TetrisSettings,
+		ObjectiveID:       ot.ID,
+		ObjectiveSettings: settings,
+		Actions:           data.Actions,
+		Checksums:         data.Checksums,
+		Pauses:            data.Pauses,
+		Board:             data.Board,
+	}, nil
+}
@@ -0,0 +1,289 @@
+package main
+
+import "fmt"
+
+// How long a replay keeps running after its last action if the objective
+// never ends the game on its own.
+const REPLAY_TRAILING_FRAMES = 60 * FRAMES_PER_SECOND
+
+// ReplayPlayer re-simulates a replay one frame at a time. It has no notion of
+// the countdown or of real time, so it is used both to drive the replay
+// viewer and to run replays headlessly.
+type ReplayPlayer struct {
+	Data      ReplayData
+	Field     *TetrisField
+	Objective Objective
+
+	// Checksums of the state during playback, recorded the same way as in a
+	// live game
+	Checksums []StateChecksum
+	// Set once playback is found to differ from the recorded game
+	Desync *DesyncError
+
+	audio           AudioService
+	started         bool
+	actionPointer   int
+	checksumPointer int
+}
+
+// DesyncError reports that replaying a game did not reproduce the state that
+// was recorded. The simulation diverged somewhere after LastGoodFrame and no
+// later than Frame.
+type DesyncError struct {
+	LastGoodFrame int64
+	Frame         int64
+}
+
+func (de *DesyncError) Error() string {
+	return fmt.Sprintf(
+		"Replay desynced between frame %v and frame %v",
+		de.LastGoodFrame,
+		de.Frame,
+	)
+}
+
+func NewReplayPlayer(data ReplayData) *ReplayPlayer {
+	rp := &ReplayPlayer{
+		Data:  data,
+		audio: &NullAudioEngine{},
+	}
+	rp.Field = NewTetrisField(data.Seed, data.TetrisSettings)
+	if data.Board != nil {
+		rp.Field.LoadBoard(data.Board)
+	}
+	rp.Objective = data.ObjectiveSettings.Init(rp.Field)
+
+	return rp
+}
+
+func (rp *ReplayPlayer) RegisterAudio(audio AudioService) {
+	rp.audio = audio
+	rp.Field.RegisterAudio(audio)
+}
+
+// Reset puts the field back into its state before the first piece spawned.
+func (rp *ReplayPlayer) Reset() {
+	rp.Field.HandleReset(rp.Data.Seed)
+	if rp.Data.Board != nil {
+		rp.Field.LoadBoard(rp.Data.Board)
+	}
+	rp.Objective = rp.Data.ObjectiveSettings.Init(rp.Field)
+	rp.started = false
+	rp.actionPointer = 0
+	rp.checksumPointer = 0
+	rp.Checksums = nil
+	rp.Desync = nil
+}
+
+// Start spawns the first piece, which is what happens when the countdown
+// ends in a live game.
+func (rp *ReplayPlayer) Start() {
+	rp.started = true
+	rp.Field.gameStarted = true
+	rp.Field.GetRandomPiece()
+}
+
+func (rp *ReplayPlayer) Started() bool {
+	return rp.started
+}
+
+func (rp *ReplayPlayer) Frame() int64 {
+	return rp.Field.frameCount
+}
+
+func (rp *ReplayPlayer) lastActionFrame() int64 {
+	if len(rp.Data.Actions) == 0 {
+		return 0
+	}
+	return rp.Data.Actions[len(rp.Data.Actions)-1].Frame
+}
+
+// Done reports whether stepping further would not change anything.
+func (rp *ReplayPlayer) Done() bool {
+	if rp.Field.gameOver {
+		return true
+	}
+
+	return rp.actionPointer == len(rp.Data.Actions) &&
+		rp.Field.frameCount > rp.lastActionFrame()+REPLAY_TRAILING_FRAMES
+}
+
+// Step performs every action recorded on the current frame and then advances
+// the simulation by one frame.
+func (rp *ReplayPlayer) Step() {
+	if !rp.started {
+		rp.Start()
+	}
+
+	for rp.actionPointer < len(rp.Data.Actions) &&
+		rp.Field.frameCount == rp.Data.Actions[rp.actionPointer].Frame {
+		act := rp.Data.Actions[rp.actionPointer]
+		rp.Objective.HandleAction(act.Action, rp.Field)
+		rp.actionPointer++
+	}
+
+	rp.Objective.Update(rp.Field)
+
+	rp.Checksums = AppendChecksum(rp.Checksums, rp.Field)
+	rp.verify()
+}
+
+// Compares the checksums recorded so far with the ones stored in the replay.
+func (rp *ReplayPlayer) verify() {
+	for rp.checksumPointer < len(rp.Data.Checksums) {
+		expected := rp.Data.Checksums[rp.checksumPointer]
+		if expected.Frame > rp.Field.frameCount {
+			return
+		}
+
+		var actual *StateChecksum
+		for i := len(rp.Checksums) - 1; i >= 0; i-- {
+			if rp.Checksums[i].Frame == expected.Frame {
+				actual = &rp.Checksums[i]
+				break
+			}
+		}
+
+		if rp.Desync == nil && (actual == nil || actual.Hash != expected.Hash) {
+			var lastGood int64
+			if rp.checksumPointer > 0 {
+				lastGood = rp.Data.Checksums[rp.checksumPointer-1].Frame
+			}
+			rp.Desync = &DesyncError{
+				LastGoodFrame: lastGood,
+				Frame:         expected.Frame,
+			}
+		}
+		rp.checksumPointer++
+	}
+}
+
+// SeekToFrame fast-forwards the simulation until the given frame. Seeking
+// backwards re-simulates the replay from its seed.
+func (rp *ReplayPlayer) SeekToFrame(frame int64) {
+	rp.seek(
+		frame < rp.Field.frameCount,
+		func() bool { return rp.Field.frameCount >= frame },
+	)
+}
+
+// SeekToPiece fast-forwards the simulation until the given number of pieces
+// has been placed.
+func (rp *ReplayPlayer) SeekToPiece(piece int64) {
+	rp.seek(
+		piece < rp.Field.pieceCount,
+		func() bool { return rp.Field.pieceCount >= piece },
+	)
+}
+
+func (rp *ReplayPlayer) seek(rewind bool, reached func() bool) {
+	if rewind {
+		rp.Reset()
+	}
+	if !rp.started {
+		rp.Start()
+	}
+
+	// Don't play every sound effect that happened along the way
+	rp.Field.RegisterAudio(&NullAudioEngine{})
+	for !reached() && !rp.Done() {
+		rp.Step()
+	}
+	rp.Field.RegisterAudio(rp.audio)
+}
+
+// Simulate plays the whole replay headlessly and returns the player in its
+// final state.
+func (rd *ReplayData) Simulate() *ReplayPlayer {
+	rp := NewReplayPlayer(*rd)
+	rp.Start()
+	for !rp.Done() {
+		rp.Step()
+	}
+
+	return rp
+}
+
+// Verify plays the whole replay headlessly and checks that it reproduces the
+// recorded game, returning a *DesyncError if it doesn't.
+func (rd *ReplayData) Verify() error {
+	return rd.Simulate().Verify()
+}
+
+// Verify checks a finished playback against the recorded game.
+func (rp *ReplayPlayer) Verify() error {
+	if rp.Desync != nil {
+		return rp.Desync
+	}
+	// The recorded game went on longer than the simulation did
+	if rp.checksumPointer < len(rp.Data.Checksums) {
+		return &DesyncError{
+			LastGoodFrame: rp.Field.frameCount,
+			Frame:         rp.Data.Checksums[rp.checksumPointer].Frame,
+		}
+	}
+
+	return nil
+}
+
+// LineFrames returns the frame on which the replay reached each line count,
+// indexed by line count.
+func (rd *ReplayData) LineFrames() []int64 {
+	rp := NewReplayPlayer(*rd)
+	frames := []int64{0}
+	rp.Field.AddLineClearHandler(func(garbage, nonGarbage int) {
+		for int64(len(frames)) <= rp.Field.lines {
+			frames = append(frames, rp.Field.frameCount)
+		}
+	})
+
+	rp.Start()
+	for !rp.Done() {
+		rp.Step()
+	}
+
+	return frames
+}
+
+// ReplaySummary describes how a replayed game ended.
+type ReplaySummary struct {
+	ObjectiveID ObjectiveID
+	Category    string
+	Frames      int64
+	Score       int64
+	Lines       int64
+	Pieces      int64
+	Failed      bool
+	Reason      string
+	// Whether the game started from a board rather than an empty field
+	CustomBoard bool
+}
+
+func (rp *ReplayPlayer) Summary() ReplaySummary {
+	summary := SummarizeField(
+		rp.Data.ObjectiveID,
+		rp.Data.ObjectiveSettings,
+		rp.Field,
+	)
+	summary.CustomBoard = rp.Data.Board != nil
+	return summary
+}
+
+// SummarizeField describes the current state of a game played with the given
+// objective. A game that hasn't ended counts as failed.
+func SummarizeField(
+	id ObjectiveID,
+	settings ObjectiveSettings,
+	es *TetrisField,
+) ReplaySummary {
+	return ReplaySummary{
+		ObjectiveID: id,
+		Category:    ObjectiveCategory(id, settings),
+		Frames:      es.frameCount,
+		Score:       es.score,
+		Lines:       es.lines,
+		Pieces:      es.pieceCount,
+		Failed:      es.failed || !es.gameOver,
+		Reason:      es.gameOverReason,
+	}
+}
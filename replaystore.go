@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const REPLAY_DIR = "replays"
+
+// Stores favorites alongside the replays. Hidden so it isn't listed as a
+// replay itself.
+const REPLAY_INDEX_FILE = ".index.json"
+
+// Default number of replays kept per mode when pruning, on top of favorites
+// and personal bests
+const DEFAULT_PRUNE_KEEP = 10
+
+// Wraps errors from replays that exist but can't be decoded
+var ErrCorruptReplay = errors.New("Corrupt replay")
+
+// ReplayEntry is a file in the replay directory. Its summary is only known
+// once the replay has been decoded and simulated.
+type ReplayEntry struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+
+	Decoded bool
+	Err     error
+	Summary ReplaySummary
+}
+
+// ListReplays returns every replay file, newest first, without decoding them.
+func ListReplays() ([]*ReplayEntry, error) {
+	dirEntries, err := os.ReadDir(REPLAY_DIR)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*ReplayEntry, 0, len(dirEntries))
+	for _, e := range dirEntries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		entry := &ReplayEntry{Name: e.Name()}
+		if info, err := e.Info(); err == nil {
+			entry.Size = info.Size()
+			entry.ModTime = info.ModTime()
+		}
+		entries = append(entries, entry)
+	}
+
+	slices.SortFunc(entries, func(a, b *ReplayEntry) int {
+		return b.ModTime.Compare(a.ModTime)
+	})
+
+	return entries, nil
+}
+
+// Decode reads and simulates the replay to fill in its summary.
+func (entry *ReplayEntry) Decode() {
+	entry.Summary, entry.Err = SummarizeReplay(entry.Name)
+	entry.Decoded = true
+}
+
+// SummarizeReplay plays back the replay with the given name to find out how
+// the game ended.
+func SummarizeReplay(name string) (ReplaySummary, error) {
+	replayData, err := ReadReplayFile(name)
+	if err != nil {
+		return ReplaySummary{}, err
+	}
+	return replayData.Simulate().Summary(), nil
+}
+
+func replayPath(name string) string {
+	return filepath.Join(REPLAY_DIR, name)
+}
+
+// ReadReplayFile decodes the replay with the given name from the replay
+// directory.
+func ReadReplayFile(name string) (*ReplayData, error) {
+	file, err := os.Open(replayPath(name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	replayData, err := StdDecoder(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptReplay, err)
+	}
+	return replayData, nil
+}
+
+// SaveReplay writes a replay to a new file in the replay directory and
+// returns its name. Nothing is left behind if the replay can't be written.
+func SaveReplay(rd *ReplayData) (string, error) {
+	err := os.MkdirAll(REPLAY_DIR, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("rp-%v", time.Now())
+	file, err := os.Create(replayPath(name))
+	if err != nil {
+		return "", err
+	}
+
+	err = StdEncoder(rd, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(replayPath(name))
+		return "", err
+	}
+
+	return name, nil
+}
+
+// ReplayIndex holds what the user has recorded about their replays.
+type ReplayIndex struct {
+	Favorites map[string]bool
+}
+
+func LoadReplayIndex() (*ReplayIndex, error) {
+	ri := &ReplayIndex{
+		Favorites: make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(replayPath(REPLAY_INDEX_FILE))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ri, nil
+	}
+	if err != nil {
+		return ri, err
+	}
+
+	err = json.Unmarshal(data, ri)
+	if ri.Favorites == nil {
+		ri.Favorites = make(map[string]bool)
+	}
+	return ri, err
+}
+
+func (ri *ReplayIndex) Save() error {
+	data, err := json.MarshalIndent(ri, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(REPLAY_DIR, 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(replayPath(REPLAY_INDEX_FILE), data, 0644)
+}
+
+func (ri *ReplayIndex) ToggleFavorite(name string) error {
+	err := ValidateReplayName(name)
+	if err != nil {
+		return err
+	}
+
+	if ri.Favorites[name] {
+		delete(ri.Favorites, name)
+	} else {
+		ri.Favorites[name] = true
+	}
+	return ri.Save()
+}
+
+func (ri *ReplayIndex) DeleteReplay(name string) error {
+	err := ValidateReplayName(name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(replayPath(name))
+	if err != nil {
+		return err
+	}
+	relinkRecords(name, "")
+
+	if ri.Favorites[name] {
+		delete(ri.Favorites, name)
+		return ri.Save()
+	}
+	return nil
+}
+
+// ValidateReplayName checks that a name can be used for a file in the replay
+// directory.
+func ValidateReplayName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("name cannot be empty")
+	}
+	if strings.HasPrefix(name, ".") {
+		return errors.New("name cannot start with a dot")
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return errors.New("name cannot contain slashes")
+	}
+	return nil
+}
+
+func (ri *ReplayIndex) RenameReplay(oldName, newName string) error {
+	err := ValidateReplayName(oldName)
+	if err != nil {
+		return err
+	}
+	err = ValidateReplayName(newName)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(replayPath(newName))
+	if err == nil {
+		return fmt.Errorf("a replay named %q already exists", newName)
+	}
+
+	err = os.Rename(replayPath(oldName), replayPath(newName))
+	if err != nil {
+		return err
+	}
+	relinkRecords(oldName, newName)
+
+	if ri.Favorites[oldName] {
+		delete(ri.Favorites, oldName)
+		ri.Favorites[newName] = true
+		return ri.Save()
+	}
+	return nil
+}
+
+// PlanPrune picks the replays to delete so that only the newest keep replays
+// of each mode remain, along with favorites and the best replay of every
+// objective and settings combination. Entries must already be decoded;
+// replays that can't be decoded are never pruned.
+func (ri *ReplayIndex) PlanPrune(entries []*ReplayEntry, keep int) []*ReplayEntry {
+	kept := make(map[*ReplayEntry]bool)
+
+	// Personal bests
+	best := make(map[string]*ReplayEntry)
+	for _, entry := range entries {
+		if !entry.Decoded || entry.Err != nil {
+			kept[entry] = true
+			continue
+		}
+		if ri.Favorites[entry.Name] {
+			kept[entry] = true
+		}
+
+		ot, ok := GetObjectiveType(entry.Summary.ObjectiveID)
+		if !ok {
+			kept[entry] = true
+			continue
+		}
+		current, ok := best[entry.Summary.Category]
+		if !ok || ot.Better(entry.Summary, current.Summary) {
+			best[entry.Summary.Category] = entry
+		}
+	}
+	for _, entry := range best {
+		kept[entry] = true
+	}
+
+	// Newest replays of each mode
+	newest := slices.Clone(entries)
+	slices.SortStableFunc(newest, func(a, b *ReplayEntry) int {
+		return b.ModTime.Compare(a.ModTime)
+	})
+	perMode := make(map[ObjectiveID]int)
+	for _, entry := range newest {
+		if kept[entry] {
+			continue
+		}
+		if perMode[entry.Summary.ObjectiveID] < keep {
+			perMode[entry.Summary.ObjectiveID]++
+			kept[entry] = true
+		}
+	}
+
+	pruned := make([]*ReplayEntry, 0)
+	for _, entry := range entries {
+		if !kept[entry] {
+			pruned = append(pruned, entry)
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestValidateReplayName(t *testing.T) {
+	for _, name := range []string{"rp-1", "my run", "sprint 40L.bin"} {
+		if err := ValidateReplayName(name); err != nil {
+			t.Errorf("%q: unexpected error %v", name, err)
+		}
+	}
+	for _, name := range []string{
+		"", "  ", ".records.json", "..", "../x", "a/b", `a\b`,
+	} {
+		if ValidateReplayName(name) == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+}
+
+func TestReplayIndexRejectsBadNames(t *testing.T) {
+	ri := &ReplayIndex{Favorites: make(map[string]bool)}
+	if ri.DeleteReplay("../x") == nil {
+		t.Error("deleted a replay outside the replay directory")
+	}
+	if ri.DeleteReplay(RECORDS_FILE) == nil {
+		t.Error("deleted the records file")
+	}
+	if ri.RenameReplay(RECORDS_FILE, "records") == nil {
+		t.Error("renamed the records file")
+	}
+	if ri.ToggleFavorite("../x") == nil {
+		t.Error("starred a replay outside the replay directory")
+	}
+}
+
+func TestPlanPrune(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(name string, age int, id ObjectiveID, frames int64) *ReplayEntry {
+		return &ReplayEntry{
+			Name:    name,
+			ModTime: start.Add(-time.Duration(age) * time.Hour),
+			Decoded: true,
+			Summary: ReplaySummary{
+				ObjectiveID: id,
+				Category:    id.String(),
+				Frames:      frames,
+				Score:       frames,
+			},
+		}
+	}
+
+	entries := []*ReplayEntry{
+		entry("sprint-new", 0, LineClear, 5000),
+		entry("sprint-best", 5, LineClear, 3000),
+		entry("sprint-old", 3, LineClear, 6000),
+		entry("sprint-fav", 4, LineClear, 7000),
+		entry("sprint-oldest", 6, LineClear, 8000),
+		entry("endless-new", 1, Endless, 100),
+		entry("endless-old", 2, Endless, 200),
+		{Name: "undecoded", ModTime: start.Add(-10 * time.Hour)},
+		{
+			Name:    "corrupt",
+			ModTime: start.Add(-10 * time.Hour),
+			Decoded: true,
+			Err:     ErrCorruptReplay,
+		},
+	}
+	ri := &ReplayIndex{Favorites: map[string]bool{"sprint-fav": true}}
+
+	var pruned []string
+	for _, entry := range ri.PlanPrune(entries, 1) {
+		pruned = append(pruned, entry.Name)
+	}
+	slices.Sort(pruned)
+
+	// Kept: the newest of each mode, the best of each category, favorites,
+	// and anything that couldn't be decoded
+	want := []string{"sprint-old", "sprint-oldest"}
+	if !slices.Equal(pruned, want) {
+		t.Errorf("pruned %v, want %v", pruned, want)
+	}
+
+	if got := ri.PlanPrune(entries, 10); len(got) != 0 {
+		t.Errorf("pruned %d replays with a large keep count", len(got))
+	}
+}
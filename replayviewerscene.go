@@ -1,9 +1,24 @@
 package main
 
 import (
+	"fmt"
 	"math"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+var PLAYBACK_SPEEDS = []float64{0.25, 0.5, 1, 2, 4, 8}
+
+// Index into PLAYBACK_SPEEDS
+const DEFAULT_PLAYBACK_SPEED = 2
+
+type SeekMode int8
+
+const (
+	NoSeek SeekMode = iota
+	SeekPiece
+	SeekTime
 )
 
 // TODO: replays look deterministic but implementing some basic tests
@@ -13,53 +28,194 @@ import (
 // so it might be economical to merge the two with dependency injection or
 // something
 type ReplayViewerScene struct {
-	app *App
-	es  *TetrisField
+	app    *App
+	player *ReplayPlayer
 
-	objective Objective
+	// Scene to go back to when leaving, or nil for the main menu
+	back Scene
 
 	replayData     ReplayData
 	countdownTimer float64
 	countdownSpeed float64
 	gameStarted    bool
 
-	actionPointer int
+	totalFrames int64
+	totalPieces int64
+	desync      error
+
+	paused       bool
+	speedIdx     int
+	frameBalance float64
+
+	seekMode  SeekMode
+	seekField FormField
+	seekValue int64
+
+	// Area the scene was last drawn in, used to hit-test mouse clicks
+	area Area
 }
 
 func (rvs *ReplayViewerScene) Init(
 	app *App,
 	replayData ReplayData,
+	back Scene,
 ) {
 	rvs.app = app
+	rvs.back = back
 	rvs.replayData = replayData
-	rvs.es = NewTetrisField(rvs.replayData.Seed, rvs.replayData.TetrisSettings)
-	rvs.es.RegisterAudio(app.Audio)
+	rvs.player = NewReplayPlayer(replayData)
+	rvs.player.RegisterAudio(app.Audio)
 
-	rvs.objective = rvs.replayData.ObjectiveSettings.Init(rvs.es)
+	final := replayData.Simulate()
+	rvs.totalFrames = final.Field.frameCount
+	rvs.totalPieces = final.Field.pieceCount
+	rvs.desync = final.Verify()
+	if rvs.desync != nil {
+		rvs.app.Logger.Printf("%v\n", rvs.desync)
+	}
 
 	rvs.countdownTimer = COUNTDOWN_DURATION_SECS
 	rvs.countdownSpeed = COUNTDOWN_SPEED
 
 	rvs.gameStarted = false
+	rvs.speedIdx = DEFAULT_PLAYBACK_SPEED
 }
 
 func (rvs *ReplayViewerScene) HandleEvent(ev tcell.Event) {
+	if rvs.seekMode != NoSeek {
+		rvs.seekField.Field.HandleInput(ev)
+		return
+	}
+
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		if IsRune(ev, 'g') || IsRune(ev, 'G') {
+			rvs.OpenSeekPrompt(SeekPiece)
+		} else if IsRune(ev, 't') || IsRune(ev, 'T') {
+			rvs.OpenSeekPrompt(SeekTime)
+		}
+	case *tcell.EventMouse:
+		if ev.Buttons()&tcell.Button1 == 0 {
+			return
+		}
+		x, y := ev.Position()
+		bar := rvs.scrubBarArea()
+		if bar.Contains(x, y) {
+			// A bar one cell wide has nowhere to seek to but the start
+			frac := 0.0
+			if bar.Width > 1 {
+				frac = float64(x-bar.X) / float64(bar.Width-1)
+			}
+			rvs.SeekToFrame(int64(math.Round(frac * float64(rvs.totalFrames))))
+		}
+	}
 }
 
 func (rvs *ReplayViewerScene) HandleAction(act Action) {
+	if rvs.seekMode != NoSeek {
+		switch act {
+		case MenuConfirm:
+			rvs.ConfirmSeek()
+		case Quit:
+			rvs.seekMode = NoSeek
+		}
+		return
+	}
+
 	switch act {
 	case Quit:
-		rvs.app.OpenMenuScene()
+		if rvs.back != nil {
+			rvs.app.NextScene = rvs.back
+		} else {
+			rvs.app.OpenMenuScene()
+		}
 	case Reset:
-		rvs.es.HandleReset(rvs.replayData.Seed)
-		rvs.objective = rvs.replayData.ObjectiveSettings.Init(rvs.es)
+		rvs.player.Reset()
 
 		rvs.countdownTimer = COUNTDOWN_DURATION_SECS
 		rvs.countdownSpeed = RESET_COUNTDOWN_SPEED
 		rvs.gameStarted = false
+	case Pause, HardDrop:
+		rvs.paused = !rvs.paused
+	case MoveRight:
+		rvs.paused = true
+		rvs.SeekToFrame(rvs.player.Frame() + 1)
+	case MoveLeft:
+		rvs.paused = true
+		rvs.SeekToFrame(rvs.player.Frame() - 1)
+	case MoveUp:
+		rvs.speedIdx = min(len(PLAYBACK_SPEEDS)-1, rvs.speedIdx+1)
+	case MoveDown:
+		rvs.speedIdx = max(0, rvs.speedIdx-1)
+	case MenuConfirm:
+		rvs.TakeOver()
+	}
+}
+
+// TakeOver hands control of the game to the player at the current frame.
+func (rvs *ReplayViewerScene) TakeOver() {
+	if rvs.player.Field.gameOver {
+		return
+	}
+
+	rvs.startPlayback()
+	if !rvs.player.Started() {
+		rvs.player.Start()
+	}
+
+	rvs.app.OpenGameSceneFromReplay(rvs.player)
+}
+
+func (rvs *ReplayViewerScene) OpenSeekPrompt(mode SeekMode) {
+	rvs.seekMode = mode
+	rvs.paused = true
+
+	var name string
+	var value, limit int64
+	if mode == SeekPiece {
+		name = "Go to piece"
+		value = rvs.player.Field.pieceCount
+		limit = rvs.totalPieces
+	} else {
+		name = "Go to second"
+		value = rvs.player.Frame() / FRAMES_PER_SECOND
+		limit = rvs.totalFrames / FRAMES_PER_SECOND
+	}
+
+	rvs.seekValue = value
+	rvs.seekField = NewIntegerField(
+		name,
+		value,
+		func(value int64) {
+			rvs.seekValue = value
+		},
+		WithMin(0),
+		WithMax(limit),
+	)
+}
 
-		rvs.actionPointer = 0
+func (rvs *ReplayViewerScene) ConfirmSeek() {
+	switch rvs.seekMode {
+	case SeekPiece:
+		rvs.startPlayback()
+		rvs.player.SeekToPiece(rvs.seekValue)
+	case SeekTime:
+		rvs.SeekToFrame(rvs.seekValue * FRAMES_PER_SECOND)
 	}
+
+	rvs.seekMode = NoSeek
+}
+
+// SeekToFrame jumps to the given frame, skipping the countdown if it is still
+// running.
+func (rvs *ReplayViewerScene) SeekToFrame(frame int64) {
+	rvs.startPlayback()
+	rvs.player.SeekToFrame(max(0, min(rvs.totalFrames, frame)))
+}
+
+func (rvs *ReplayViewerScene) startPlayback() {
+	rvs.gameStarted = true
+	rvs.frameBalance = 0
 }
 
 func (rvs *ReplayViewerScene) Update() {
@@ -67,30 +223,34 @@ func (rvs *ReplayViewerScene) Update() {
 		rvs.countdownTimer -= (UPDATE_TICK_RATE_MS / 1000.0) * rvs.countdownSpeed
 		if rvs.countdownTimer < 0 {
 			rvs.gameStarted = true
-			rvs.es.gameStarted = true
-			rvs.es.GetRandomPiece()
+			rvs.player.Start()
 		}
 
 		return
 	}
 
-	for rvs.actionPointer < len(rvs.replayData.Actions) &&
-		rvs.es.frameCount == rvs.replayData.Actions[rvs.actionPointer].Frame {
-		act := rvs.replayData.Actions[rvs.actionPointer]
-		rvs.objective.HandleAction(act.Action, rvs.es)
-		rvs.actionPointer++
+	if rvs.paused {
+		return
 	}
 
-	rvs.objective.Update(rvs.es)
+	rvs.frameBalance += PLAYBACK_SPEEDS[rvs.speedIdx]
+	for rvs.frameBalance >= 1 {
+		rvs.frameBalance -= 1
+		if !rvs.player.Done() {
+			rvs.player.Step()
+		}
+	}
 }
 
 func (rvs *ReplayViewerScene) Draw(sw, sh int, rr Area, lag float64) {
+	rvs.area = rr
+
 	playingField := rr.Inset(BOARD_WIDTH, BOARD_HEIGHT+4)
 	anchorX := playingField.X - 2
 	anchorY := playingField.Bottom() - 2
 
-	rvs.es.Draw(sw, sh, playingField, lag)
-	DrawStats(rvs.objective.GetStats(), anchorX, anchorY)
+	rvs.player.Field.Draw(sw, sh, playingField, lag)
+	DrawStats(rvs.player.Objective.GetStats(), anchorX, anchorY)
 
 	if !rvs.gameStarted {
 		textAnchorX := playingField.X + BOARD_WIDTH/2
@@ -112,6 +272,110 @@ func (rvs *ReplayViewerScene) Draw(sw, sh int, rr Area, lag float64) {
 			rvs.countdownTimer-math.Floor(rvs.countdownTimer),
 		)
 	}
+
+	rvs.DrawScrubBar()
+	rvs.DrawPlaybackStatus(rr)
+}
+
+// The scrub bar sits in the bottom left corner, below the stats, with the
+// time under it.
+func (rvs *ReplayViewerScene) scrubBarArea() Area {
+	rr := rvs.area
+	playingField := rr.Inset(BOARD_WIDTH, BOARD_HEIGHT+4)
+
+	return Area{
+		X:      rr.X + 1,
+		Y:      rr.Bottom() - 2,
+		Width:  playingField.X - rr.X - 4,
+		Height: 1,
+	}
+}
+
+func (rvs *ReplayViewerScene) DrawScrubBar() {
+	bar := rvs.scrubBarArea()
+
+	frame := rvs.player.Frame()
+	position := 0
+	if rvs.totalFrames > 0 {
+		position = int(float64(bar.Width-1) *
+			float64(min(frame, rvs.totalFrames)) / float64(rvs.totalFrames))
+	}
+
+	for i := 0; i < bar.Width; i++ {
+		r := '-'
+		if i < position {
+			r = '='
+		} else if i == position {
+			r = '|'
+		}
+		Screen.SetContent(bar.X+i, bar.Y, r, nil, defStyle)
+	}
+
+	// Mark where the player paused
+	if rvs.totalFrames > 0 {
+		for _, ps := range rvs.replayData.Pauses {
+			i := int(float64(bar.Width-1) *
+				float64(min(ps.Frame, rvs.totalFrames)) / float64(rvs.totalFrames))
+			if i != position {
+				Screen.SetContent(bar.X+i, bar.Y, '!', nil, defStyle.Foreground(tcell.ColorYellow))
+			}
+		}
+	}
+
+	SetString(
+		bar.X,
+		bar.Y+1,
+		fmt.Sprintf(
+			"%s / %s",
+			FormatFrames(frame),
+			FormatFrames(rvs.totalFrames),
+		),
+		defStyle,
+	)
+}
+
+func (rvs *ReplayViewerScene) DrawPlaybackStatus(rr Area) {
+	x := rr.Right() - 28
+	// The status line, with the help or seek prompt under it on the last
+	// row inside the area
+	y := rr.Bottom() - 2
+
+	if rvs.player.Desync != nil {
+		SetString(
+			x, y-1,
+			fmt.Sprintf("DESYNC near %v", FormatFrames(rvs.player.Desync.Frame)),
+			defStyle.Foreground(tcell.ColorRed),
+		)
+	} else if rvs.desync != nil {
+		SetString(x, y-1, "Replay will desync", defStyle.Foreground(tcell.ColorRed))
+	}
+
+	status := "PLAYING"
+	if rvs.paused {
+		status = "PAUSED"
+	}
+	SetString(
+		x, y,
+		fmt.Sprintf(
+			"%s %vx  piece %v/%v",
+			status,
+			PLAYBACK_SPEEDS[rvs.speedIdx],
+			rvs.player.Field.pieceCount,
+			rvs.totalPieces,
+		),
+		defStyle,
+	)
+
+	if rvs.seekMode != NoSeek {
+		SetString(x, y+1, rvs.seekField.Name, defStyle.Reverse(true))
+		rvs.seekField.Field.Draw(
+			x+1+runewidth.StringWidth(rvs.seekField.Name),
+			y+1,
+			true,
+		)
+	} else {
+		SetString(x, y+1, "</>:step g/t:seek enter:play", defStyle)
+	}
 }
 
 func (rvs *ReplayViewerScene) DrawProgressBar(
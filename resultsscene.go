@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type ResultsOption int8
+
+const (
+	ResultsRetry ResultsOption = iota
+	ResultsRetrySeed
+	ResultsWatchReplay
+	ResultsExportFumen
+	ResultsMenu
+)
+
+var RESULTS_OPTION_NAMES = []string{
+	"Retry",
+	"Retry same seed",
+	"Watch replay",
+	"Export fumen",
+	"Menu",
+}
+
+// Width of the labels in the statistics columns
+const RESULTS_LABEL_WIDTH = 16
+
+// ResultsScene shows how a game went once it's over, and compares it against
+// the records for the same objective and settings.
+type ResultsScene struct {
+	app  *App
+	game *GameScene
+
+	replayData *ReplayData
+	objective  ObjectiveType
+	summary    ReplaySummary
+	stats      GameStats
+	finesse    int64
+
+	// Best result before this game, if any, and where this one placed
+	best *Record
+	rank int
+	// Games started from a board have no records to compare against
+	customBoard bool
+	// Puzzle that was played, if any
+	puzzle *Puzzle
+	// Daily challenge that was played, if any
+	daily *DailyChallenge
+
+	menuFocus ResultsOption
+}
+
+func (rs *ResultsScene) Init(app *App, game *GameScene, replayData *ReplayData) {
+	rs.app = app
+	rs.game = game
+	rs.replayData = replayData
+	rs.objective, _ = GetObjectiveType(game.objectiveID)
+	rs.summary = SummarizeField(game.objectiveID, game.objectiveSettings, game.es)
+	rs.stats = game.es.stats
+	rs.finesse = game.es.finesse
+
+	rs.best = game.best
+	rs.rank = game.recordRank
+	rs.customBoard = game.board != nil
+	rs.puzzle = game.puzzle
+	rs.daily = game.daily
+}
+
+func (rs *ResultsScene) HandleEvent(ev tcell.Event) {
+	if ev, ok := ev.(*tcell.EventKey); ok {
+		if len(rs.game.unsaved) > 0 && (IsRune(ev, 's') || IsRune(ev, 'S')) {
+			rs.game.SaveReplays()
+		}
+	}
+}
+
+func (rs *ResultsScene) HandleAction(act Action) {
+	switch act {
+	case MoveUp:
+		rs.menuFocus = max(0, rs.menuFocus-1)
+	case MoveDown:
+		rs.menuFocus = min(ResultsOption(len(RESULTS_OPTION_NAMES)-1), rs.menuFocus+1)
+	case Reset:
+		rs.Retry()
+	case RetrySeed:
+		rs.RetrySeed()
+	case Quit:
+		rs.game.Quit()
+	case MenuConfirm:
+		switch rs.menuFocus {
+		case ResultsRetry:
+			rs.Retry()
+		case ResultsRetrySeed:
+			rs.RetrySeed()
+		case ResultsWatchReplay:
+			rs.app.OpenReplayViewerScene(*rs.replayData, rs)
+		case ResultsExportFumen:
+			rs.ExportFumen()
+		case ResultsMenu:
+			rs.game.Quit()
+		}
+	}
+}
+
+// Retry starts another game with the same settings.
+func (rs *ResultsScene) Retry() {
+	rs.game.Restart()
+	rs.app.NextScene = rs.game
+}
+
+// RetrySeed starts another game with the same settings and pieces.
+func (rs *ResultsScene) RetrySeed() {
+	rs.game.RetrySeed()
+	rs.app.NextScene = rs.game
+}
+
+// ExportFumen writes every placement of the game as a Fumen, named after its
+// replay if that was saved.
+func (rs *ResultsScene) ExportFumen() {
+	name := fmt.Sprintf("rp-%v", time.Now().Format("2006-01-02 15.04.05"))
+	if !slices.Contains(rs.game.unsaved, rs.replayData) && rs.game.lastSaved != "" {
+		name = rs.game.lastSaved
+	}
+
+	path, err := ExportFumenFile(name, EncodeFumen(rs.replayData.FumenPages()))
+	if err != nil {
+		rs.app.ReportError("Could not export fumen", err)
+		return
+	}
+	rs.app.ShowToast(fmt.Sprintf("Wrote %v", path))
+}
+
+func (rs *ResultsScene) Update() {
+}
+
+// Returns the statistics shown in the left column, as label and value pairs.
+func (rs *ResultsScene) overview() [][2]string {
+	seconds := float64(rs.summary.Frames) / float64(FRAMES_PER_SECOND)
+	pps, lpm := 0.0, 0.0
+	if seconds > 0 {
+		pps = float64(rs.summary.Pieces) / seconds
+		lpm = float64(rs.summary.Lines) / (seconds / 60)
+	}
+
+	return [][2]string{
+		{"Time", FormatFrames(rs.summary.Frames)},
+		{"Score", fmt.Sprintf("%d", rs.summary.Score)},
+		{"Lines", fmt.Sprintf("%d", rs.summary.Lines)},
+		{"Pieces", fmt.Sprintf("%d", rs.summary.Pieces)},
+		{"Pieces/sec", fmt.Sprintf("%.2f", pps)},
+		{"Lines/min", fmt.Sprintf("%.2f", lpm)},
+		{"Finesse faults", fmt.Sprintf("%d", rs.finesse)},
+		{"Max combo", fmt.Sprintf("%d", rs.stats.MaxCombo)},
+		{"Holds", fmt.Sprintf("%d", rs.stats.Holds)},
+		{"Seed", fmt.Sprintf("%d", rs.replayData.Seed)},
+	}
+}
+
+// Returns the line clear breakdown shown in the right column.
+func (rs *ResultsScene) clears() [][2]string {
+	return [][2]string{
+		{"Singles", fmt.Sprintf("%d", rs.stats.Singles)},
+		{"Doubles", fmt.Sprintf("%d", rs.stats.Doubles)},
+		{"Triples", fmt.Sprintf("%d", rs.stats.Triples)},
+		{"Tetrises", fmt.Sprintf("%d", rs.stats.Tetrises)},
+		{"Spins", fmt.Sprintf("%d", rs.stats.Spins)},
+	}
+}
+
+// FormatResult describes a result by whatever its objective is ranked on.
+func FormatResult(ot ObjectiveType, summary ReplaySummary) string {
+	switch ot.Rank {
+	case RankByScore:
+		return fmt.Sprintf("%d pts", summary.Score)
+	case RankBySurvival:
+		return FormatFrames(summary.Frames)
+	default:
+		if summary.Failed {
+			return fmt.Sprintf("Failed @%dL", summary.Lines)
+		}
+		return FormatFrames(summary.Frames)
+	}
+}
+
+// Describes how far the result is from the personal best.
+func (rs *ResultsScene) bestDelta() string {
+	a, b := rs.summary, rs.best.Summary
+	switch rs.objective.Rank {
+	case RankByScore:
+		return fmt.Sprintf("%+d pts", a.Score-b.Score)
+	case RankBySurvival:
+		return fmt.Sprintf(
+			"%+.3fs",
+			float64(a.Frames-b.Frames)/float64(FRAMES_PER_SECOND),
+		)
+	default:
+		if a.Failed {
+			return fmt.Sprintf("%+dL", a.Lines-b.Lines)
+		}
+		return fmt.Sprintf(
+			"%+.3fs",
+			float64(a.Frames-b.Frames)/float64(FRAMES_PER_SECOND),
+		)
+	}
+}
+
+func drawLabeledValues(x, y int, rows [][2]string) {
+	for i, row := range rows {
+		SetString(x, y+i, row[0], defStyle.Dim(true))
+		SetString(x+RESULTS_LABEL_WIDTH, y+i, row[1], defStyle)
+	}
+}
+
+func (rs *ResultsScene) Draw(sw, sh int, rr Area, lag float64) {
+	title := "FINISHED"
+	titleStyle := defStyle.Bold(true)
+	if rs.summary.Failed {
+		title = "GAME OVER"
+		titleStyle = titleStyle.Foreground(tcell.ColorRed)
+	}
+	category := rs.summary.Category
+	if rs.puzzle != nil {
+		title = "SOLVED"
+		if rs.summary.Failed {
+			title = "NOT SOLVED"
+		}
+		category = fmt.Sprintf("%v: %v", rs.puzzle.Title, rs.puzzle.Settings.Describe())
+	}
+	if rs.daily != nil {
+		category = fmt.Sprintf("Daily %v: %v", rs.daily.Date, category)
+	}
+	SetString(rr.X, rr.Y, title, titleStyle)
+	if rs.game.dailyBest {
+		SetString(
+			rr.X+len(title)+2,
+			rr.Y,
+			"BEST TODAY!",
+			defStyle.Bold(true).Foreground(tcell.ColorYellow),
+		)
+	}
+	SetString(rr.X, rr.Y+1, category, defStyle)
+	SetString(rr.X, rr.Y+2, rs.summary.Reason, defStyle.Dim(true))
+
+	drawLabeledValues(rr.X+2, rr.Y+4, rs.overview())
+
+	rightX := rr.X + rr.Width/2
+	SetString(rightX, rr.Y+4, "Line clears", defStyle.Underline(true))
+	drawLabeledValues(rightX, rr.Y+5, rs.clears())
+
+	SetString(rightX, rr.Y+11, "Personal best", defStyle.Underline(true))
+	switch {
+	case rs.rank == 0:
+		SetString(
+			rightX,
+			rr.Y+12,
+			"NEW PB!",
+			defStyle.Bold(true).Foreground(tcell.ColorYellow),
+		)
+	case rs.rank > 0:
+		SetString(
+			rightX,
+			rr.Y+12,
+			fmt.Sprintf("#%d in the records", rs.rank+1),
+			defStyle.Bold(true),
+		)
+	case rs.game.firstSolved:
+		SetString(
+			rightX,
+			rr.Y+12,
+			"First solve!",
+			defStyle.Bold(true).Foreground(tcell.ColorYellow),
+		)
+	case rs.puzzle != nil:
+		SetString(rightX, rr.Y+12, "Puzzle", defStyle.Dim(true))
+	case rs.customBoard:
+		SetString(rightX, rr.Y+12, "Custom board", defStyle.Dim(true))
+	case rs.best == nil:
+		SetString(rightX, rr.Y+12, "None yet", defStyle.Dim(true))
+	}
+	if rs.best != nil {
+		prefix := ""
+		if rs.rank == 0 {
+			prefix = "was "
+		}
+		SetString(
+			rightX,
+			rr.Y+13,
+			fmt.Sprintf(
+				"%v%v (%v)",
+				prefix,
+				FormatResult(rs.objective, rs.best.Summary),
+				rs.bestDelta(),
+			),
+			defStyle,
+		)
+	}
+
+	optionsY := rr.Y + 15
+	for i, name := range RESULTS_OPTION_NAMES {
+		style := defStyle
+		y := optionsY + i
+		if ResultsOption(i) == rs.menuFocus {
+			style = style.Reverse(true)
+			Screen.SetContent(rr.X, y, '*', nil, defStyle)
+		}
+		SetString(rr.X+2, y, name, style)
+	}
+
+	if len(rs.game.unsaved) > 0 {
+		SetString(
+			rr.X,
+			optionsY+len(RESULTS_OPTION_NAMES)+1,
+			"Replay not saved - s:retry",
+			defStyle.Foreground(tcell.ColorRed),
+		)
+	}
+}
+
+func (rs *ResultsScene) Cleanup() {
+}
@@ -10,6 +10,13 @@ type Scene interface {
 	Cleanup()
 }
 
+// KeyCapturer is implemented by scenes that sometimes need every key press
+// as a raw event, such as when the player is choosing a key to bind. While
+// capturing, keys aren't turned into actions and can't close the game.
+type KeyCapturer interface {
+	CapturingKeys() bool
+}
+
 type NullScene struct {
 }
 
@@ -20,6 +20,7 @@ func (sas *ScoreAttackSettings) Init(es *TetrisField) Objective {
 			CreateCountdownStat(es, sas.Duration),
 			CreateLinesStat(es),
 			CreatePiecesStat(es),
+			CreateFinesseStat(es),
 		},
 	}
 
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Bumped whenever the layout of the settings file changes in a way older
+// versions of the game can't read
+const SETTINGS_VERSION = 1
+
+// Location of the settings file inside the user's config directory
+const SETTINGS_DIR = "go-tetris"
+const SETTINGS_FILE = "settings.json"
+
+type AudioSettings struct {
+	// Volumes in percent
+	SoundVolume int64
+	MusicVolume int64
+}
+
+type DisplaySettings struct {
+	Particles      bool
+	SnapIndicators bool
+	// Flash pieces placed with more inputs than needed
+	FinesseFlash bool
+}
+
+var DefaultDisplaySettings = DisplaySettings{
+	Particles:      true,
+	SnapIndicators: true,
+}
+
+// Display options currently in effect. Read by the drawing code.
+var Display = DefaultDisplaySettings
+
+// Settings is everything about the game that is remembered between runs.
+// Settings for each mode are keyed by objective name, so the file stays
+// readable and doesn't depend on the order of ObjectiveTypes.
+type Settings struct {
+	Version int
+
+	TetrisSettings    map[string]GlobalTetrisSettings
+	ObjectiveSettings map[string]json.RawMessage
+
+	KeyBindings map[Action][]KeyBinding
+	Audio       AudioSettings
+	Display     DisplaySettings
+
+	path string
+	// Set when the file on disk can't safely be overwritten
+	readOnly bool
+}
+
+func DefaultSettings() *Settings {
+	bindings := make(map[Action][]KeyBinding, len(DefaultKeyBindings))
+	for act, keys := range DefaultKeyBindings {
+		bindings[act] = append([]KeyBinding(nil), keys...)
+	}
+
+	return &Settings{
+		Version:           SETTINGS_VERSION,
+		TetrisSettings:    make(map[string]GlobalTetrisSettings),
+		ObjectiveSettings: make(map[string]json.RawMessage),
+		KeyBindings:       bindings,
+		Audio: AudioSettings{
+			SoundVolume: 100,
+			MusicVolume: 100,
+		},
+		Display: DefaultDisplaySettings,
+	}
+}
+
+func SettingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, SETTINGS_DIR, SETTINGS_FILE), nil
+}
+
+// LoadSettings reads the settings file, filling in defaults for anything it
+// doesn't mention. Usable settings are returned even when there is an error.
+// A file that can't be parsed is moved aside so it isn't lost, and a file
+// from a newer version of the game is left alone.
+func LoadSettings() (*Settings, error) {
+	settings := DefaultSettings()
+
+	path, err := SettingsPath()
+	if err != nil {
+		settings.readOnly = true
+		return settings, err
+	}
+	settings.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		settings.readOnly = true
+		return settings, err
+	}
+
+	var header struct {
+		Version int
+	}
+	err = json.Unmarshal(data, &header)
+	if err == nil && header.Version > SETTINGS_VERSION {
+		settings.readOnly = true
+		return settings, fmt.Errorf(
+			"Settings file is version %v, newer than the supported version %v",
+			header.Version,
+			SETTINGS_VERSION,
+		)
+	}
+	if err == nil {
+		// Saved bindings replace the defaults rather than adding to them
+		settings.KeyBindings = nil
+		err = json.Unmarshal(data, settings)
+	}
+	if err != nil {
+		backup := path + ".bak"
+		settings = DefaultSettings()
+		settings.path = path
+		if renameErr := os.Rename(path, backup); renameErr != nil {
+			settings.readOnly = true
+			return settings, err
+		}
+		return settings, fmt.Errorf("%w (moved to %v)", err, backup)
+	}
+
+	// A hand-edited file may have emptied out whole sections
+	if settings.TetrisSettings == nil {
+		settings.TetrisSettings = make(map[string]GlobalTetrisSettings)
+	}
+	if settings.ObjectiveSettings == nil {
+		settings.ObjectiveSettings = make(map[string]json.RawMessage)
+	}
+	if settings.KeyBindings == nil {
+		settings.KeyBindings = DefaultSettings().KeyBindings
+	} else {
+		settings.KeyBindings = MergeDefaultBindings(settings.KeyBindings)
+	}
+
+	settings.Version = SETTINGS_VERSION
+	return settings, nil
+}
+
+// Save writes the settings file, replacing it in one step so that a crash
+// never leaves it half written.
+func (s *Settings) Save() error {
+	if s.readOnly {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// TetrisSettingsFor returns the tetris settings last used for a mode.
+func (s *Settings) TetrisSettingsFor(id ObjectiveID) GlobalTetrisSettings {
+	if gts, ok := s.TetrisSettings[id.String()]; ok {
+		return gts
+	}
+	return DefaultTetrisSettings
+}
+
+// ObjectiveSettingsFor returns the objective settings last used for a mode,
+// or the mode's defaults if they were never changed or can't be read.
+func (s *Settings) ObjectiveSettingsFor(ot ObjectiveType) ObjectiveSettings {
+	settings := ot.New()
+	if data, ok := s.ObjectiveSettings[ot.Name]; ok {
+		if json.Unmarshal(data, settings) != nil {
+			return ot.New()
+		}
+	}
+	return settings
+}
+
+// SetGameSettings remembers the settings a mode was last played with.
+func (s *Settings) SetGameSettings(
+	id ObjectiveID,
+	gts GlobalTetrisSettings,
+	objectiveSettings ObjectiveSettings,
+) error {
+	data, err := json.Marshal(objectiveSettings)
+	if err != nil {
+		return err
+	}
+
+	s.TetrisSettings[id.String()] = gts
+	s.ObjectiveSettings[id.String()] = data
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Points the settings file at a fresh directory and returns its path.
+func tempSettingsPath(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+
+	path, err := SettingsPath()
+	if err != nil {
+		t.Fatalf("No settings path: %v", err)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSettingsMissing(t *testing.T) {
+	tempSettingsPath(t)
+
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if settings.readOnly {
+		t.Errorf("Settings are read-only without a file")
+	}
+	if !slices.Equal(settings.KeyBindings[Reset], DefaultKeyBindings[Reset]) {
+		t.Errorf("Default bindings missing: %v", settings.KeyBindings[Reset])
+	}
+}
+
+func TestLoadSettingsNewerVersion(t *testing.T) {
+	path := tempSettingsPath(t)
+	data := []byte(`{"Version": 99, "Audio": {"SoundVolume": 5}}`)
+	os.WriteFile(path, data, 0644)
+
+	settings, err := LoadSettings()
+	if err == nil {
+		t.Errorf("Expected an error for a newer version")
+	}
+	if !settings.readOnly {
+		t.Errorf("Settings from a newer version should be read-only")
+	}
+	if settings.Audio.SoundVolume != 100 {
+		t.Errorf("Newer settings were used: %v", settings.Audio)
+	}
+
+	settings.Save()
+	after, _ := os.ReadFile(path)
+	if string(after) != string(data) {
+		t.Errorf("Newer settings file was overwritten")
+	}
+}
+
+func TestLoadSettingsCorrupt(t *testing.T) {
+	path := tempSettingsPath(t)
+	data := []byte(`{"Version": 1, "Audio": `)
+	os.WriteFile(path, data, 0644)
+
+	settings, err := LoadSettings()
+	if err == nil {
+		t.Errorf("Expected an error for a corrupt file")
+	}
+	if settings.readOnly {
+		t.Errorf("Settings should be writable once the file is moved aside")
+	}
+
+	backup, readErr := os.ReadFile(path + ".bak")
+	if readErr != nil || string(backup) != string(data) {
+		t.Errorf("Corrupt file wasn't backed up: %v", readErr)
+	}
+	if _, statErr := os.Stat(path); statErr == nil {
+		t.Errorf("Corrupt file was left in place")
+	}
+}
+
+func TestLoadSettingsBindings(t *testing.T) {
+	path := tempSettingsPath(t)
+	// Rebinds t to rotating, and says nothing of RetrySeed, whose default is
+	// also t
+	os.WriteFile(path, []byte(`{
+		"Version": 1,
+		"KeyBindings": {
+			"RotateCW": ["x", "t"],
+			"Pause": []
+		}
+	}`), 0644)
+
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	bindings := settings.KeyBindings
+	if !slices.Equal(bindings[RotateCW], []KeyBinding{RuneBinding('x'), RuneBinding('t')}) {
+		t.Errorf("Saved binding changed: %v", bindings[RotateCW])
+	}
+	if len(bindings[Pause]) != 0 {
+		t.Errorf("Cleared binding came back: %v", bindings[Pause])
+	}
+	if !slices.Equal(bindings[RetrySeed], []KeyBinding{RuneBinding('T')}) {
+		t.Errorf("Default binding wasn't merged around the saved one: %v",
+			bindings[RetrySeed])
+	}
+	if !slices.Equal(bindings[Reset], DefaultKeyBindings[Reset]) {
+		t.Errorf("Default binding missing: %v", bindings[Reset])
+	}
+}
+
+func TestDefaultKeyBindingsUnique(t *testing.T) {
+	boundTo := make(map[KeyBinding]Action)
+	for act, keys := range DefaultKeyBindings {
+		for _, key := range keys {
+			if other, ok := boundTo[key]; ok {
+				t.Errorf("%v is bound to %v and %v", key, act.ToString(),
+					other.ToString())
+			}
+			boundTo[key] = act
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import "slices"
+
+// FieldSnapshot is a copy of everything about a field that changes as pieces
+// are placed: the board, the piece in play, the queue and the randomizer
+// that fills it, and the score. Restoring one puts its piece back at the top
+// of the board.
+type FieldSnapshot struct {
+	grid           Grid[int]
+	cpIdx          int
+	nextPieces     []int
+	pieceGenerator PieceGenerator
+	holdPiece      int
+	usedHoldPiece  bool
+
+	score      int64
+	lines      int64
+	pieceCount int64
+	combo      int
+	level      int64
+	fallRate   int64
+	stats      GameStats
+	finesse    int64
+
+	garbageQueue   []int
+	maxStackHeight int
+}
+
+// Snapshot copies the current state of the field.
+func (es *TetrisField) Snapshot() FieldSnapshot {
+	return FieldSnapshot{
+		grid:           es.grid.Clone(),
+		cpIdx:          es.cpIdx,
+		nextPieces:     slices.Clone(es.nextPieces),
+		pieceGenerator: es.pieceGenerator.Clone(),
+		holdPiece:      es.holdPiece,
+		usedHoldPiece:  es.usedHoldPiece,
+
+		score:      es.score,
+		lines:      es.lines,
+		pieceCount: es.pieceCount,
+		combo:      es.combo,
+		level:      es.level,
+		fallRate:   es.fallRate,
+		stats:      es.stats,
+		finesse:    es.finesse,
+
+		garbageQueue:   slices.Clone(es.garbageQueue),
+		maxStackHeight: es.maxStackHeight,
+	}
+}
+
+// Restore returns the field to a snapshot. The snapshot is copied, so it can
+// be restored again later. A game that ended is taken up again.
+func (es *TetrisField) Restore(snap FieldSnapshot) {
+	es.grid = snap.grid.Clone()
+	es.nextPieces = slices.Clone(snap.nextPieces)
+	es.pieceGenerator = snap.pieceGenerator.Clone()
+	es.holdPiece = snap.holdPiece
+	es.usedHoldPiece = snap.usedHoldPiece
+
+	es.score = snap.score
+	es.lines = snap.lines
+	es.pieceCount = snap.pieceCount
+	es.combo = snap.combo
+	es.level = snap.level
+	es.fallRate = snap.fallRate
+	es.stats = snap.stats
+	es.finesse = snap.finesse
+	es.finesseFlash = 0
+
+	es.garbageQueue = slices.Clone(snap.garbageQueue)
+	es.maxStackHeight = snap.maxStackHeight
+
+	es.gameOver = false
+	es.failed = false
+	es.gameOverReason = ""
+
+	es.gravityTimer = BASE_GRAVITY_UNIT
+	es.SetPiece(snap.cpIdx)
+}
+
+// ReseedPieces replaces the queue with pieces from a new randomizer.
+func (es *TetrisField) ReseedPieces(seed int64) {
+	gen := NewBagRandomizer(seed, 1)
+	es.pieceGenerator = &gen
+	es.FillNextPieces()
+}
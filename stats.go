@@ -5,6 +5,18 @@ import (
 	"math"
 )
 
+// GameStats counts what happened over the course of a game.
+type GameStats struct {
+	Singles  int64
+	Doubles  int64
+	Triples  int64
+	Tetrises int64
+	// Line clears made by rotating a piece into a spot it can't move out of
+	Spins    int64
+	MaxCombo int
+	Holds    int64
+}
+
 type Stat struct {
 	Name    string
 	Compute func() []string
@@ -91,22 +103,33 @@ func CreatePiecesStat(es *TetrisField) Stat {
 	}
 }
 
+// FormatTime formats a duration in milliseconds as m:ss.mmm.
+func FormatTime(rawTime float64) string {
+	timeMinutes := math.Trunc(rawTime / (60 * 1000))
+	timeSeconds := math.Trunc((rawTime - timeMinutes*60*1000) / 1000)
+	timeMillis := math.Trunc((rawTime - timeMinutes*60*1000 -
+		timeSeconds*1000))
+
+	return fmt.Sprintf("%0d:%02d.%03d",
+		int(timeMinutes),
+		int(timeSeconds),
+		int(timeMillis),
+	)
+}
+
+// FormatFrames formats a number of frames as m:ss.mmm.
+func FormatFrames(frames int64) string {
+	return FormatTime(float64(frames) * UPDATE_TICK_RATE_MS)
+}
+
 func CreateElapsedTimeStat(es *TetrisField) Stat {
 	return Stat{
 		Compute: func() []string {
 			rawTime := float64(es.frameCount) * UPDATE_TICK_RATE_MS
-			timeMinutes := math.Trunc(rawTime / (60 * 1000))
-			timeSeconds := math.Trunc((rawTime - timeMinutes*60*1000) / 1000)
-			timeMillis := math.Trunc((rawTime - timeMinutes*60*1000 -
-				timeSeconds*1000))
 
 			return []string{
 				"TIME",
-				fmt.Sprintf("%0d:%02d.%03d",
-					int(timeMinutes),
-					int(timeSeconds),
-					int(timeMillis),
-				),
+				FormatTime(rawTime),
 			}
 		},
 	}
@@ -117,18 +140,21 @@ func CreateCountdownStat(es *TetrisField, duration int64) Stat {
 		Compute: func() []string {
 			rawTime := float64(es.frameCount) * UPDATE_TICK_RATE_MS
 			rawTime = float64(duration)*1000 - rawTime
-			timeMinutes := math.Trunc(rawTime / (60 * 1000))
-			timeSeconds := math.Trunc((rawTime - timeMinutes*60*1000) / 1000)
-			timeMillis := math.Trunc((rawTime - timeMinutes*60*1000 -
-				timeSeconds*1000))
 
 			return []string{
 				"TIME",
-				fmt.Sprintf("%0d:%02d.%03d",
-					int(timeMinutes),
-					int(timeSeconds),
-					int(timeMillis),
-				),
+				FormatTime(rawTime),
+			}
+		},
+	}
+}
+
+func CreateFinesseStat(es *TetrisField) Stat {
+	return Stat{
+		Compute: func() []string {
+			return []string{
+				"FINESSE",
+				fmt.Sprintf("%d", es.finesse),
 			}
 		},
 	}
@@ -21,6 +21,7 @@ func (ss *SurvivalSettings) Init(es *TetrisField) Objective {
 			CreateElapsedTimeStat(es),
 			CreateLinesStat(es),
 			CreatePiecesStat(es),
+			CreateFinesseStat(es),
 		},
 	}
 }
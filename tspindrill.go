@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+)
+
+// Points for completing each kind of setup, multiplied by the level
+const (
+	TSPIN_MINI_SCORE   = 200
+	TSPIN_DOUBLE_SCORE = 1200
+	TSPIN_TRIPLE_SCORE = 1600
+)
+
+// Most rows of garbage laid under a setup
+const TSPIN_DRILL_BASE_ROWS = 3
+
+type DrillSetup int8
+
+const (
+	MiniSetup DrillSetup = iota
+	DoubleSetup
+	TripleSetup
+)
+
+func (ds DrillSetup) String() string {
+	switch ds {
+	case MiniSetup:
+		return "T-spin mini"
+	case DoubleSetup:
+		return "T-spin double"
+	case TripleSetup:
+		return "T-spin triple"
+	}
+	return fmt.Sprintf("DrillSetup(%d)", int8(ds))
+}
+
+type TSpinDrillSettings struct {
+	Rounds  int64
+	Minis   bool
+	Doubles bool
+	Triples bool
+}
+
+// TSpinDrillObjective builds a board with a T-spin slot for every round and
+// makes sure a T is on its way. A round ends when a T locks, and scores if
+// it made the spin the slot was built for.
+type TSpinDrillObjective struct {
+	Rounds int64
+	setups []DrillSetup
+
+	setup DrillSetup
+	round int64
+	hits  int64
+	// Whether the last round was hit, and whether a T has locked since the
+	// current board was built
+	lastHit  bool
+	resolved bool
+
+	stats []Stat
+}
+
+func (ts *TSpinDrillSettings) Init(es *TetrisField) Objective {
+	to := &TSpinDrillObjective{
+		Rounds: ts.Rounds,
+	}
+	if ts.Minis {
+		to.setups = append(to.setups, MiniSetup)
+	}
+	if ts.Doubles || len(to.setups) == 0 && !ts.Triples {
+		to.setups = append(to.setups, DoubleSetup)
+	}
+	if ts.Triples {
+		to.setups = append(to.setups, TripleSetup)
+	}
+
+	to.stats = []Stat{
+		CreateElapsedTimeStat(es),
+		CreatePiecesStat(es),
+		to.DrillStat(),
+	}
+
+	es.AddLineClearHandler(func(garbage, nonGarbage int) {
+		if es.cpIdx == T_PIECE {
+			to.Resolve(es, garbage+nonGarbage)
+		}
+	})
+	es.AddSpawnHandler(func() {
+		if to.resolved && !es.gameOver {
+			to.NextRound(es)
+		}
+	})
+
+	to.NextRound(es)
+	return to
+}
+
+func (to *TSpinDrillObjective) GetStats() []Stat {
+	return to.stats
+}
+
+// Resolve scores the round once a T has locked.
+func (to *TSpinDrillObjective) Resolve(es *TetrisField, lines int) {
+	spin, wantLines, score := FullSpin, 2, int64(TSPIN_DOUBLE_SCORE)
+	switch to.setup {
+	case MiniSetup:
+		spin, wantLines, score = MiniSpin, 1, TSPIN_MINI_SCORE
+	case TripleSetup:
+		wantLines, score = 3, TSPIN_TRIPLE_SCORE
+	}
+
+	to.lastHit = es.lastLockTSpin == spin && lines == wantLines
+	if to.lastHit {
+		to.hits++
+		es.score += score * es.level
+	}
+	to.resolved = true
+
+	if to.round >= to.Rounds {
+		es.ObjectiveComplete(fmt.Sprintf("%d/%d spins", to.hits, to.round))
+	}
+}
+
+// NextRound replaces the board with a new setup, and puts a T in the queue
+// if there isn't one coming already.
+func (to *TSpinDrillObjective) NextRound(es *TetrisField) {
+	to.round++
+	to.resolved = false
+	to.setup = to.setups[es.garbageRng.Intn(len(to.setups))]
+
+	board := TSpinSetupBoard(to.setup, es.garbageRng)
+	board.Hold = es.holdPiece
+	es.LoadBoard(board)
+
+	hasT := es.holdPiece == T_PIECE ||
+		es.gameStarted && es.cpIdx == T_PIECE ||
+		slices.Contains(es.nextPieces, T_PIECE)
+	if !hasT {
+		es.nextPieces[es.garbageRng.Intn(len(es.nextPieces))] = T_PIECE
+	}
+
+	if es.gameStarted {
+		es.SetHardDropHeight()
+	}
+}
+
+// TSpinSetupBoard builds a slot for a setup on top of a few rows of garbage,
+// facing either way.
+//
+// A double has a T-shaped hole two rows deep under an overhang, and a triple
+// a three row deep hole under a roof that the T kicks down into. A mini has a
+// flat hole with an overhang at one end, which the T drops in beside and
+// then rotates under.
+func TSpinSetupBoard(setup DrillSetup, rng *rand.Rand) *Board {
+	// A row that is filled, or empty, except for the given columns
+	row := func(filled bool, except ...int) []bool {
+		cells := make([]bool, BOARD_WIDTH)
+		for x := range cells {
+			cells[x] = filled != slices.Contains(except, x)
+		}
+		return cells
+	}
+	columns := func(from, to int) []int {
+		xs := make([]int, 0, to-from)
+		for x := from; x < to; x++ {
+			xs = append(xs, x)
+		}
+		return xs
+	}
+
+	// Rows from the bottom up, with the overhang on the left, and the column
+	// the T rests on the garbage through
+	var rows [][]bool
+	var column int
+	switch setup {
+	case MiniSetup:
+		x := rng.Intn(BOARD_WIDTH - 3)
+		column = x + 1
+		hole := rng.Intn(BOARD_WIDTH - 3)
+		if hole >= x {
+			hole += 3
+		}
+		rows = [][]bool{
+			row(true, hole),
+			row(true, x, x+1, x+2),
+			row(true, x+1, x+2),
+		}
+	case DoubleSetup:
+		column = 1 + rng.Intn(BOARD_WIDTH-2)
+		rows = [][]bool{
+			row(true, column),
+			row(true, column-1, column, column+1),
+			row(false, columns(0, column)...),
+		}
+	case TripleSetup:
+		column = 3 + rng.Intn(BOARD_WIDTH-4)
+		rows = [][]bool{
+			row(true, column),
+			row(true, column-1, column),
+			row(true, column),
+			row(false, columns(column+1, BOARD_WIDTH)...),
+			row(false, columns(column, BOARD_WIDTH)...),
+		}
+	}
+
+	mirror := rng.Intn(2) == 0
+	if mirror {
+		column = BOARD_WIDTH - 1 - column
+		for _, row := range rows {
+			slices.Reverse(row)
+		}
+	}
+
+	// Rows are pushed in at the bottom, so the top of the slot goes in first
+	b := NewBoard()
+	for i := len(rows) - 1; i >= 0; i-- {
+		b.AddGarbageRow(-1)
+		for x, filled := range rows[i] {
+			if !filled {
+				b.Grid.Set(x, b.Grid.Height-1, 0)
+			}
+		}
+	}
+	for i := 1 + rng.Intn(TSPIN_DRILL_BASE_ROWS); i > 0; i-- {
+		hole := rng.Intn(BOARD_WIDTH - 1)
+		if hole >= column {
+			hole++
+		}
+		b.AddGarbageRow(hole)
+	}
+	return b
+}
+
+func (to *TSpinDrillObjective) Update(es *TetrisField) {
+	if es.gameOver {
+		return
+	}
+	es.Update()
+}
+
+func (to *TSpinDrillObjective) HandleAction(act Action, es *TetrisField) {
+	es.HandleAction(act)
+}
+
+func (to *TSpinDrillObjective) DrillStat() Stat {
+	return Stat{
+		Compute: func() []string {
+			last := ""
+			if to.round > 1 || to.resolved {
+				last = "missed"
+				if to.lastHit {
+					last = "hit!"
+				}
+			}
+
+			return []string{
+				"T-SPIN DRILL",
+				to.setup.String(),
+				fmt.Sprintf("round %d/%d", min(to.round, to.Rounds), to.Rounds),
+				fmt.Sprintf("%d hit", to.hits),
+				last,
+			}
+		},
+	}
+}
+
+func (ts *TSpinDrillSettings) CreateFormFields() []FormField {
+	return []FormField{
+		NewIntegerField(
+			"Rounds",
+			ts.Rounds,
+			func(value int64) {
+				ts.Rounds = value
+			},
+			WithMin(1),
+		),
+		NewBooleanField(
+			"Minis",
+			ts.Minis,
+			func(value bool) {
+				ts.Minis = value
+			},
+		),
+		NewBooleanField(
+			"Doubles",
+			ts.Doubles,
+			func(value bool) {
+				ts.Doubles = value
+			},
+		),
+		NewBooleanField(
+			"Triples",
+			ts.Triples,
+			func(value bool) {
+				ts.Triples = value
+			},
+		),
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestTSpinKind(t *testing.T) {
+	// Corners are numbered as in tSpinCorners: clockwise from the top left
+	for _, tc := range []struct {
+		name     string
+		piece    int
+		rot      int
+		corners  []int
+		rotated  bool
+		lastKick int
+		want     SpinKind
+	}{
+		{"not a T", 1, 0, []int{0, 1, 2, 3}, true, 0, NoSpin},
+		{"moved last", T_PIECE, 0, []int{0, 1, 2}, false, 0, NoSpin},
+		{"two corners", T_PIECE, 0, []int{2, 3}, true, 0, NoSpin},
+		{"both front corners", T_PIECE, 0, []int{0, 1, 3}, true, 0, FullSpin},
+		{"one front corner", T_PIECE, 0, []int{1, 2, 3}, true, 0, MiniSpin},
+		{"one front corner, last kick", T_PIECE, 0, []int{1, 2, 3}, true, 4, FullSpin},
+		{"pointing right", T_PIECE, 1, []int{1, 2, 3}, true, 0, FullSpin},
+		{"pointing down", T_PIECE, 2, []int{0, 1, 2}, true, 0, MiniSpin},
+		{"pointing left", T_PIECE, 3, []int{0, 2, 3}, true, 0, FullSpin},
+		{"all four corners", T_PIECE, 2, []int{0, 1, 2, 3}, true, 0, FullSpin},
+	} {
+		es := NewTetrisField(1, DefaultTetrisSettings)
+		es.cpIdx, es.cpRot = tc.piece, tc.rot
+		es.cpGrid = Pieces[tc.piece][tc.rot]
+		es.cpX, es.cpY = 3, BOARD_HEIGHT
+		es.lastMoveRotation = tc.rotated
+		es.lastKick = tc.lastKick
+		for _, i := range tc.corners {
+			c := tSpinCorners[i]
+			es.grid.Set(es.cpX+c.X, es.cpY+c.Y, GARBAGE_CELL)
+		}
+
+		if got := es.TSpinKind(); got != tc.want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestTSpinAfterDrop(t *testing.T) {
+	es := NewTetrisField(1, DefaultTetrisSettings)
+	// A gap for the T's stem at the bottom, and a cell that fills a third
+	// corner once it's down
+	bottom := es.grid.Height - 1
+	for x := 0; x < BOARD_WIDTH; x++ {
+		if x != 4 {
+			es.grid.Set(x, bottom, GARBAGE_CELL)
+		}
+	}
+	es.grid.Set(3, bottom-2, GARBAGE_CELL)
+
+	es.SetPiece(T_PIECE)
+	es.HandleAction(RotateCW)
+	for es.airborne {
+		es.HandleAction(MoveDown)
+	}
+	if kind := es.TSpinKind(); kind != NoSpin {
+		t.Errorf("T turned at spawn and soft dropped counts as %v", kind)
+	}
+
+	es.SetPiece(T_PIECE)
+	es.HandleAction(RotateCW)
+	es.HandleAction(HardDrop)
+	if es.lastLockTSpin != NoSpin || es.lastLockSpin {
+		t.Errorf("T turned at spawn and hard dropped counts as %v", es.lastLockTSpin)
+	}
+}
+
+type tSpinState struct {
+	x, y, rot int
+}
+
+// Searches every place a T can be moved to on the field from where it
+// spawns, and calls found for each one it can be rotated into and lock in.
+func searchTSpins(es *TetrisField, found func()) {
+	start := tSpinState{BOARD_WIDTH/2 - 2, BOARD_HEIGHT - 2, 0}
+	seen := map[tSpinState]bool{start: true}
+	queue := []tSpinState{start}
+
+	set := func(s tSpinState) {
+		es.cpIdx, es.cpRot = T_PIECE, s.rot
+		es.cpGrid = Pieces[T_PIECE][s.rot]
+		es.cpX, es.cpY = s.x, s.y
+		es.airborne = true
+		es.floorKicked = false
+	}
+	visit := func(s tSpinState) {
+		if !seen[s] {
+			seen[s] = true
+			queue = append(queue, s)
+		}
+	}
+
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+
+		for _, move := range []Position{{X: -1}, {X: 1}, {Y: 1}} {
+			next := tSpinState{s.x + move.X, s.y + move.Y, s.rot}
+			if !es.CheckCollision(Pieces[T_PIECE][s.rot], next.x, next.y) {
+				visit(next)
+			}
+		}
+
+		for _, offset := range []int{1, -1} {
+			set(s)
+			es.lastMoveRotation = false
+			es.Rotate(offset)
+			if !es.lastMoveRotation {
+				continue
+			}
+			visit(tSpinState{es.cpX, es.cpY, es.cpRot})
+			if es.CheckCollision(es.cpGrid, es.cpX, es.cpY+1) {
+				found()
+			}
+		}
+	}
+}
+
+// Counts the lines the current piece would clear if it locked.
+func linesCleared(es *TetrisField) int {
+	lines := 0
+	for y := es.cpY; y < es.cpY+es.cpGrid.Height && y < es.grid.Height; y++ {
+		full := y >= 0
+		for x := 0; x < BOARD_WIDTH && full; x++ {
+			px, py := x-es.cpX, y-es.cpY
+			inPiece := px >= 0 && px < es.cpGrid.Width && es.cpGrid.MustGet(px, py)
+			full = inPiece || es.grid.MustGet(x, y) != 0
+		}
+		if full {
+			lines++
+		}
+	}
+	return lines
+}
+
+func TestTSpinSetupBoard(t *testing.T) {
+	for _, tc := range []struct {
+		setup DrillSetup
+		spin  SpinKind
+		lines int
+	}{
+		{MiniSetup, MiniSpin, 1},
+		{DoubleSetup, FullSpin, 2},
+		{TripleSetup, FullSpin, 3},
+	} {
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 50; i++ {
+			es := NewTetrisField(1, DefaultTetrisSettings)
+			es.LoadBoard(TSpinSetupBoard(tc.setup, rng))
+
+			reachable := false
+			searchTSpins(es, func() {
+				if es.TSpinKind() == tc.spin && linesCleared(es) == tc.lines {
+					reachable = true
+				}
+			})
+			if !reachable {
+				t.Errorf("%v %d: no way to make the spin", tc.setup, i)
+			}
+		}
+	}
+}